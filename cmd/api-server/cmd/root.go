@@ -4,9 +4,11 @@ import (
 	"context"
 
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -32,6 +34,8 @@ func newRootCmd(opts rootCmdOpts) *cobra.Command {
 			mgr, err := opts.NewManager(opts.GetConfigOrDie(), apiserver.Options{
 				Scheme: runtime.Scheme(),
 				Port:   args.port,
+				// events are listed by the object they involve, which the cache can not select on
+				ClientDisableCacheFor: []client.Object{&corev1.Event{}},
 			})
 			if err != nil {
 				setupLog.Error(err, "unable to create api-server manager")
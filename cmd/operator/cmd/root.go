@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"time"
 
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/rest"
@@ -12,6 +13,7 @@ import (
 	"github.com/gojekfarm/darkroom-operator/cmd/version"
 	"github.com/gojekfarm/darkroom-operator/internal/controllers"
 	"github.com/gojekfarm/darkroom-operator/internal/runtime"
+	deploymentsv1alpha1 "github.com/gojekfarm/darkroom-operator/pkg/api/v1alpha1"
 	pkglog "github.com/gojekfarm/darkroom-operator/pkg/log"
 	// +kubebuilder:scaffold:imports
 )
@@ -26,6 +28,9 @@ func newRootCmd(opts rootCmdOpts) *cobra.Command {
 		healthProbeAddr      string
 		enableLeaderElection bool
 		certDir              string
+		drainPeriod          time.Duration
+		imageRepository      string
+		imageTag             string
 	}{}
 	cmd := &cobra.Command{
 		Use:   "darkroom-operator",
@@ -48,9 +53,14 @@ func newRootCmd(opts rootCmdOpts) *cobra.Command {
 			}
 
 			r := &controllers.DarkroomReconciler{
-				Client: mgr.GetClient(),
-				Log:    pkglog.Log.WithName("controllers").WithName("darkroom-reconciler"),
-				Scheme: mgr.GetScheme(),
+				Client:      mgr.GetClient(),
+				Log:         pkglog.Log.WithName("controllers").WithName("darkroom-reconciler"),
+				Scheme:      mgr.GetScheme(),
+				Recorder:    mgr.GetEventRecorderFor("darkroom-controller"),
+				DrainPeriod: args.drainPeriod,
+
+				DefaultImageRepository: args.imageRepository,
+				DefaultImageTag:        args.imageTag,
 			}
 
 			if err = r.SetupControllerWithManager(mgr); err != nil {
@@ -81,6 +91,12 @@ func newRootCmd(opts rootCmdOpts) *cobra.Command {
 	cmd.PersistentFlags().StringVar(&args.metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	cmd.PersistentFlags().StringVar(&args.healthProbeAddr, "health-probe-bind-address", ":8081", "The address the metric endpoint binds to.")
 	cmd.PersistentFlags().StringVar(&args.certDir, "cert-dir", "", "The directory containing server certificate and key.")
+	cmd.PersistentFlags().StringVar(&args.imageRepository, "default-image-repository", deploymentsv1alpha1.DefaultImageRepository,
+		"The repository of the darkroom image, unless overridden by spec.image.repository of a Darkroom.")
+	cmd.PersistentFlags().StringVar(&args.imageTag, "default-image-tag", deploymentsv1alpha1.LatestVersion,
+		"The tag of the darkroom image that an empty or latest spec.version of a Darkroom is resolved into.")
+	cmd.PersistentFlags().DurationVar(&args.drainPeriod, "drain-period", 10*time.Second,
+		"How long a deleted Darkroom keeps its workloads after its routes are removed.")
 	cmd.PersistentFlags().BoolVar(&args.enableLeaderElection, "leader-elect", false, "Enable leader election for controller manager. "+
 		"Enabling this will ensure there is only one active controller manager.")
 	return cmd
@@ -19,6 +19,7 @@ import (
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -63,6 +64,9 @@ func (s *RootCmdSuite) TestControllerStartup() {
 	mm.On("Start", mock.AnythingOfType("*context.cancelCtx")).Return(nil)
 	mm.On("GetClient").Return(mc)
 	mm.On("GetScheme").Return(internalRuntime.Scheme())
+	mm.On("GetEventRecorderFor", "darkroom-controller").Return(record.NewFakeRecorder(10))
+	mm.On("GetRESTMapper").Return(meta.NewDefaultRESTMapper(nil))
+	mm.On("GetFieldIndexer").Return(&informertest.FakeInformers{})
 	mm.On("GetConfig").Return(&rest.Config{})
 	mm.On("GetLogger").Return(zap.New(zap.UseDevMode(true)))
 	mm.On("SetFields", mock.Anything).Return(nil)
@@ -132,6 +136,9 @@ func (s *RootCmdSuite) TestControllerStartupWithManagerStartError() {
 	mm.On("Start", mock.AnythingOfType("*context.cancelCtx")).Return(startErr)
 	mm.On("GetClient").Return(mc)
 	mm.On("GetScheme").Return(internalRuntime.Scheme())
+	mm.On("GetEventRecorderFor", "darkroom-controller").Return(record.NewFakeRecorder(10))
+	mm.On("GetRESTMapper").Return(meta.NewDefaultRESTMapper(nil))
+	mm.On("GetFieldIndexer").Return(&informertest.FakeInformers{})
 	mm.On("GetConfig").Return(&rest.Config{})
 	mm.On("GetLogger").Return(zap.New(zap.UseDevMode(true)))
 	mm.On("SetFields", mock.Anything).Return(nil)
@@ -171,6 +178,9 @@ func (s *RootCmdSuite) TestControllerSetupError() {
 	mm.On("Start", mock.AnythingOfType("*context.cancelCtx")).Return(nil)
 	mm.On("GetClient").Return(mc)
 	mm.On("GetScheme").Return(internalRuntime.Scheme())
+	mm.On("GetEventRecorderFor", "darkroom-controller").Return(record.NewFakeRecorder(10))
+	mm.On("GetRESTMapper").Return(meta.NewDefaultRESTMapper(nil))
+	mm.On("GetFieldIndexer").Return(&informertest.FakeInformers{})
 	mm.On("GetConfig").Return(&rest.Config{})
 	mm.On("GetLogger").Return(zap.New(zap.UseDevMode(true)))
 	mm.On("SetFields", mock.Anything).Return(nil)
@@ -210,6 +220,9 @@ func (s *RootCmdSuite) TestControllerAddHealthzCheckError() {
 	mm.On("Start", mock.AnythingOfType("*context.cancelCtx")).Return(nil)
 	mm.On("GetClient").Return(mc)
 	mm.On("GetScheme").Return(internalRuntime.Scheme())
+	mm.On("GetEventRecorderFor", "darkroom-controller").Return(record.NewFakeRecorder(10))
+	mm.On("GetRESTMapper").Return(meta.NewDefaultRESTMapper(nil))
+	mm.On("GetFieldIndexer").Return(&informertest.FakeInformers{})
 	mm.On("GetConfig").Return(&rest.Config{})
 	mm.On("GetLogger").Return(zap.New(zap.UseDevMode(true)))
 	mm.On("SetFields", mock.Anything).Return(nil)
@@ -248,6 +261,9 @@ func (s *RootCmdSuite) TestControllerAddReadyzCheckError() {
 	mm.On("Start", mock.AnythingOfType("*context.cancelCtx")).Return(nil)
 	mm.On("GetClient").Return(mc)
 	mm.On("GetScheme").Return(internalRuntime.Scheme())
+	mm.On("GetEventRecorderFor", "darkroom-controller").Return(record.NewFakeRecorder(10))
+	mm.On("GetRESTMapper").Return(meta.NewDefaultRESTMapper(nil))
+	mm.On("GetFieldIndexer").Return(&informertest.FakeInformers{})
 	mm.On("GetConfig").Return(&rest.Config{})
 	mm.On("GetLogger").Return(zap.New(zap.UseDevMode(true)))
 	mm.On("SetFields", mock.Anything).Return(nil)
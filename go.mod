@@ -5,6 +5,8 @@ go 1.16
 require (
 	github.com/emicklei/go-restful/v3 v3.5.2
 	github.com/go-logr/logr v0.4.0
+	github.com/google/gofuzz v1.1.0
+	github.com/prometheus/client_golang v1.7.1
 	github.com/spf13/cobra v1.2.1
 	github.com/stretchr/testify v1.7.0
 	go.uber.org/zap v1.18.1
@@ -12,4 +14,5 @@ require (
 	k8s.io/apimachinery v0.20.9
 	k8s.io/client-go v0.20.9
 	sigs.k8s.io/controller-runtime v0.8.3
+	sigs.k8s.io/structured-merge-diff/v4 v4.1.2
 )
@@ -74,6 +74,38 @@ func (s *EndpointSuite) TestCreate() {
 		s.mockClient.AssertExpectations(s.T())
 	})
 
+	s.Run("AzureBlobStorage", func() {
+		s.SetupTest()
+		s.mockClient.On("Create",
+			mock.Anything,
+			mock.AnythingOfType("*v1alpha1.Darkroom"),
+			[]client.CreateOption{client.FieldOwner("api-server")},
+		).Return(nil)
+
+		azureObj := obj.DeepCopy()
+		azureObj.Spec.Source = v1alpha1.Source{
+			Type: v1alpha1.AzureBlobStorage,
+			Azure: &v1alpha1.AzureBlob{
+				AccountName:          "darkroom",
+				Container:            "images",
+				CredentialsSecretRef: &v1alpha1.CredentialsSecretRef{Name: "azure-credentials"},
+			},
+		}
+
+		b := &bytes.Buffer{}
+		s.NoError(json.NewEncoder(b).Encode(azureObj))
+
+		req := httptest.NewRequest(http.MethodPost, "/default/darkrooms", b)
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add("Accept", "*/*")
+		resp := httptest.NewRecorder()
+		s.handler.ServeHTTP(resp, req)
+
+		s.Equal(http.StatusCreated, resp.Code)
+
+		s.mockClient.AssertExpectations(s.T())
+	})
+
 	s.Run("CreateError", func() {
 		s.SetupTest()
 		s.mockClient.On("Create",
@@ -124,6 +156,33 @@ func (s *EndpointSuite) TestCreate() {
 		s.mockClient.AssertExpectations(s.T())
 	})
 
+	s.Run("AzureBlobStorageValidationError", func() {
+		s.SetupTest()
+
+		invalidObj := obj.DeepCopy()
+		invalidObj.Spec.Source = v1alpha1.Source{
+			Type:  v1alpha1.AzureBlobStorage,
+			Azure: &v1alpha1.AzureBlob{AccountName: "darkroom", Container: "images"},
+		}
+
+		b := &bytes.Buffer{}
+		s.NoError(json.NewEncoder(b).Encode(invalidObj))
+
+		req := httptest.NewRequest(http.MethodPost, "/default/darkrooms", b)
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add("Accept", "*/*")
+		resp := httptest.NewRecorder()
+		s.handler.ServeHTTP(resp, req)
+
+		s.Equal(`{
+ "message": "Unable to create instance",
+ "error": "Darkroom.deployments.gojek.io \"darkroom-create-sample\" is invalid: spec.source.azure.sasToken: Required value: sasToken or credentialsSecretRef required with Type AzureBlobStorage"
+}`, resp.Body.String())
+		s.Equal(http.StatusUnprocessableEntity, resp.Code)
+
+		s.mockClient.AssertExpectations(s.T())
+	})
+
 	s.Run("BadRequest", func() {
 		s.SetupTest()
 
@@ -0,0 +1,23 @@
+package darkroom
+
+import (
+	"github.com/emicklei/go-restful/v3"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// events lists the Events involving the named Darkroom, including those of requests rejected before it was created
+func (e *Endpoint) events(request *restful.Request, response *restful.Response) {
+	ns := request.PathParameter("namespace")
+	n := request.PathParameter("name")
+	e.respond(response, func() error {
+		el := new(corev1.EventList)
+		if err := e.client.List(request.Request.Context(), el, client.InNamespace(ns), client.MatchingFields{
+			"involvedObject.kind": "Darkroom",
+			"involvedObject.name": n,
+		}); err != nil {
+			return err
+		}
+		return response.WriteAsJson(el)
+	}, "Unable to list events of instance "+n)
+}
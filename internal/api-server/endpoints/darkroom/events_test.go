@@ -0,0 +1,84 @@
+package darkroom
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (s *EndpointSuite) TestEvents() {
+	s.Run("Success", func() {
+		s.SetupTest()
+		s.mockClient.On("List",
+			mock.Anything,
+			mock.AnythingOfType("*v1.EventList"),
+			[]client.ListOption{
+				client.InNamespace("default"),
+				client.MatchingFields{
+					"involvedObject.kind": "Darkroom",
+					"involvedObject.name": "darkroom-events-sample",
+				},
+			},
+		).Run(func(args mock.Arguments) {
+			el := args.Get(1).(*corev1.EventList)
+			el.Items = []corev1.Event{{
+				Type:    corev1.EventTypeNormal,
+				Reason:  "Created",
+				Message: "Created Deployment darkroom-events-sample",
+			}}
+		}).Return(nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/default/darkrooms/darkroom-events-sample/events", nil)
+		resp := httptest.NewRecorder()
+		s.handler.ServeHTTP(resp, req)
+
+		s.Equal(`{
+ "metadata": {},
+ "items": [
+  {
+   "metadata": {
+    "creationTimestamp": null
+   },
+   "involvedObject": {},
+   "reason": "Created",
+   "message": "Created Deployment darkroom-events-sample",
+   "source": {},
+   "firstTimestamp": null,
+   "lastTimestamp": null,
+   "type": "Normal",
+   "eventTime": null,
+   "reportingComponent": "",
+   "reportingInstance": ""
+  }
+ ]
+}`, resp.Body.String())
+		s.Equal(http.StatusOK, resp.Code)
+
+		s.mockClient.AssertExpectations(s.T())
+	})
+
+	s.Run("ListError", func() {
+		s.SetupTest()
+		s.mockClient.On("List",
+			mock.Anything,
+			mock.AnythingOfType("*v1.EventList"),
+			mock.Anything,
+		).Return(errors.New("internal error"))
+
+		req := httptest.NewRequest(http.MethodGet, "/internal-error/darkrooms/darkroom-events-sample/events", nil)
+		resp := httptest.NewRecorder()
+		s.handler.ServeHTTP(resp, req)
+
+		s.Equal(`{
+ "message": "Unable to list events of instance darkroom-events-sample",
+ "error": "internal error"
+}`, resp.Body.String())
+		s.Equal(http.StatusFailedDependency, resp.Code)
+
+		s.mockClient.AssertExpectations(s.T())
+	})
+}
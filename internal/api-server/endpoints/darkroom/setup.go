@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/emicklei/go-restful/v3"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/gojekfarm/darkroom-operator/pkg/api/v1alpha1"
@@ -31,6 +32,12 @@ func (e *Endpoint) SetupWithWS(ws *restful.WebService) {
 		Doc("Get Darkroom Instance").
 		Returns(http.StatusOK, "OK", &v1alpha1.Darkroom{}))
 
+	ws.Route(ws.GET("{namespace}/darkrooms/{name}/events").To(e.events).
+		Param(ws.PathParameter("namespace", "namespace of darkroom instances").DataType("string")).
+		Param(ws.PathParameter("name", "identifier of darkroom instance").DataType("string")).
+		Doc("List Events of Darkroom Instance").
+		Returns(http.StatusOK, "OK", &corev1.EventList{}))
+
 	ws.Route(ws.DELETE("{namespace}/darkrooms/{name}").To(e.delete).
 		Param(ws.PathParameter("namespace", "namespace of darkroom instances").DataType("string")).
 		Param(ws.PathParameter("name", "identifier of darkroom instance").DataType("string")).
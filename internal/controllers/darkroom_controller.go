@@ -26,13 +26,33 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/gojekfarm/darkroom-operator/internal/controllers/setup"
 
@@ -44,19 +64,55 @@ type DarkroomReconciler struct {
 	setup.Controller
 	setup.Webhook
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// gatewayAPI is set when the Gateway API CRDs are installed, so that HTTPRoutes can be rendered
+	gatewayAPI bool
+	// certManager is set when the cert-manager CRDs are installed, so that Certificates can be requested
+	certManager bool
+	// DrainPeriod is how long a deleted Darkroom keeps its workloads after its routes are removed,
+	// so that the ingress controller stops sending traffic before the pods go away
+	DrainPeriod time.Duration
+	// DefaultImageRepository is the repository of the darkroom image unless spec.image.repository is set
+	DefaultImageRepository string
+	// DefaultImageTag is what the defaulting webhook resolves an empty or latest spec.version into
+	DefaultImageTag string
+
+	// prometheusOperator is set when the Prometheus Operator CRDs are installed, so that ServiceMonitors can be generated
+	prometheusOperator bool
 }
 
+// fieldManager owns the fields of the objects applied by the reconciler
+const fieldManager = "darkroom-controller"
+
+var applyOptions = []client.PatchOption{client.ForceOwnership, client.FieldOwner(fieldManager)}
+
+// secretNamesIndex indexes the darkrooms by the names of the Secrets they reference, see referencedSecretNames
+const secretNamesIndex = "spec.secretNames"
+
 // +kubebuilder:rbac:groups=deployments.gojek.io,resources=darkrooms,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=deployments.gojek.io,resources=darkrooms/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=deployments.gojek.io,resources=darkrooms/finalizers,verbs=update
-// +kubebuilder:rbac:groups="",resources=configmaps;services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=configmaps;services;secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups="apps",resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="autoscaling",resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="policy",resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="networking.k8s.io",resources=ingresses;networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="cert-manager.io",resources=certificates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="gateway.networking.k8s.io",resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="monitoring.coreos.com",resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of darkroom instances inside the cluster closer to the desired state.
 //
+// Failures of the individual steps do not stop the remaining ones, they are aggregated and returned
+// so that the request is retried with backoff.
+//
+// A darkroom paused with the paused annotation is only finalized once deleted, nothing is applied for it.
+//
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.8.3/pkg/reconcile
 func (r *DarkroomReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -67,34 +123,571 @@ func (r *DarkroomReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	if err := r.Get(ctx, req.NamespacedName, &darkroom); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	if !darkroom.DeletionTimestamp.IsZero() {
+		return r.finalize(ctx, &darkroom)
+	}
+	if !controllerutil.ContainsFinalizer(&darkroom, deploymentsv1alpha1.CleanupFinalizer) {
+		patch := client.MergeFrom(darkroom.DeepCopy())
+		controllerutil.AddFinalizer(&darkroom, deploymentsv1alpha1.CleanupFinalizer)
+		if err := r.Patch(ctx, &darkroom, patch); err != nil {
+			return ctrl.Result{}, r.recordFailure(&darkroom, reasonAddFinalizerFailed, err)
+		}
+	}
+	if darkroom.Paused() {
+		return ctrl.Result{}, r.pause(ctx, &darkroom)
+	}
 
-	cfg, _ := r.desiredConfigMap(darkroom)
-	depl, _ := r.desiredDeployment(darkroom, cfg)
-	svc, _ := r.desiredService(darkroom)
+	var errs []error
+	creds, credsErr := r.credentialsSecrets(ctx, &darkroom)
+	if credsErr != nil {
+		errs = append(errs, credsErr)
+	}
+	fallbackCreds, fallbackErrs := r.fallbackSecrets(ctx, &darkroom)
+	for _, err := range fallbackErrs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
 
-	applyOptions := []client.PatchOption{client.ForceOwnership, client.FieldOwner("darkroom-controller")}
+	// the pods only run with the credentials of every source they are configured with: the ConfigMap and Deployment
+	// are held as they are while the Secrets of the source can not be read, and the fallback sources whose Secrets
+	// can not be read are left out of them until they can
+	resolved := withResolvedFallbackSources(darkroom, fallbackErrs)
+	start := time.Now()
+	cfg, cfgErr := r.desiredConfigMap(resolved)
+	depl, deplErr := r.desiredDeployment(resolved, cfg, append(creds, fallbackCreds...)...)
+	if credsErr == nil {
+		if err := r.apply(ctx, &darkroom, &cfg, cfgErr); err != nil {
+			errs = append(errs, err)
+		}
+		observeStep(&darkroom, "configmap", start)
+		start = time.Now()
+		if err := r.apply(ctx, &darkroom, &depl, deplErr); err != nil {
+			errs = append(errs, err)
+		}
+		observeStep(&darkroom, "deployment", start)
+	}
+	if err := r.reconcileAutoscaler(ctx, &darkroom); err != nil {
+		errs = append(errs, err)
+	}
+	if err := r.reconcileDisruptionBudget(ctx, &darkroom); err != nil {
+		errs = append(errs, err)
+	}
+	if err := r.reconcileNetworkPolicy(ctx, &darkroom); err != nil {
+		errs = append(errs, err)
+	}
+	start = time.Now()
+	svc, svcErr := r.desiredService(darkroom)
+	if err := r.apply(ctx, &darkroom, &svc, svcErr); err != nil {
+		errs = append(errs, err)
+	}
+	observeStep(&darkroom, "service", start)
+	if err := r.reconcileServiceMonitor(ctx, &darkroom); err != nil {
+		errs = append(errs, err)
+	}
+	cert, err := r.reconcileCertificate(ctx, &darkroom)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	domains, err := r.reconcileRoute(ctx, &darkroom, svc)
+	if err != nil {
+		errs = append(errs, err)
+	}
 
-	_ = r.Patch(ctx, &cfg, client.Apply, applyOptions...)
-	_ = r.Patch(ctx, &depl, client.Apply, applyOptions...)
-	_ = r.Patch(ctx, &svc, client.Apply, applyOptions...)
+	start = time.Now()
+	live := appsv1.Deployment{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(&depl), &live); client.IgnoreNotFound(err) != nil {
+		errs = append(errs, r.recordFailure(&darkroom, reasonGetDeploymentFailed, err))
+	}
+
+	old := darkroom.DeepCopy()
+	patch := client.MergeFrom(old)
+	darkroom.Status.Domains = domains
+	darkroom.Status.LastHandledReconcileAt = darkroom.Annotations[deploymentsv1alpha1.ReconcileAtAnnotation]
+	setPausedCondition(&darkroom, false)
+	setSourceCondition(&darkroom, credsErr)
+	setSourcesStatus(&darkroom, credsErr, fallbackErrs)
+	setCertificateCondition(&darkroom, cert, r.certManager)
+	setDeploymentStatus(&darkroom, &live)
+	setReconciledCondition(&darkroom, errs)
+	if err := r.Status().Patch(ctx, &darkroom, patch); err != nil {
+		errs = append(errs, r.recordFailure(&darkroom, reasonPatchStatusFailed, err))
+	} else {
+		r.recordTransitions(old, &darkroom)
+	}
+	observeStep(&darkroom, "status", start)
+	return ctrl.Result{}, utilerrors.NewAggregate(errs)
+}
+
+// pause reports that the reconciliation of darkroom is paused, leaving the resources it owns as they are
+func (r *DarkroomReconciler) pause(ctx context.Context, darkroom *deploymentsv1alpha1.Darkroom) error {
+	if meta.IsStatusConditionTrue(darkroom.Status.Conditions, deploymentsv1alpha1.PausedCondition) {
+		return nil
+	}
+	patch := client.MergeFrom(darkroom.DeepCopy())
+	setPausedCondition(darkroom, true)
+	if err := r.Status().Patch(ctx, darkroom, patch); err != nil {
+		return r.recordFailure(darkroom, reasonPatchStatusFailed, err)
+	}
+	r.Recorder.Eventf(darkroom, corev1.EventTypeNormal, reasonPaused,
+		"Reconciliation is paused until the %s annotation is removed", deploymentsv1alpha1.PausedAnnotation)
+	return nil
+}
+
+// finalize tears down a deleted darkroom according to spec.deletionPolicy and removes the cleanup finalizer.
+// With the Delete policy the routes are removed first and the workloads are deleted once DrainPeriod has passed
+// since the deletion, the remaining objects are left to the garbage collector. With the Orphan policy the
+// generated objects are released from darkroom instead.
+func (r *DarkroomReconciler) finalize(ctx context.Context, darkroom *deploymentsv1alpha1.Darkroom) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(darkroom, deploymentsv1alpha1.CleanupFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	if darkroom.Status.DeployState != deploymentsv1alpha1.Deleting {
+		patch := client.MergeFrom(darkroom.DeepCopy())
+		darkroom.Status.DeployState = deploymentsv1alpha1.Deleting
+		if err := r.Status().Patch(ctx, darkroom, patch); err != nil {
+			return ctrl.Result{}, r.recordFailure(darkroom, reasonPatchStatusFailed, err)
+		}
+	}
 
-	darkroom.Status.Domains = darkroom.Spec.Domains
-	darkroom.Status.DeployState = deploymentsv1alpha1.Deploying
-	_ = r.Status().Update(ctx, &darkroom)
+	if darkroom.Spec.DeletionPolicy == deploymentsv1alpha1.DeletionPolicyOrphan {
+		if err := r.orphanOwned(ctx, darkroom); err != nil {
+			return ctrl.Result{}, r.recordFailure(darkroom, reasonOrphanFailed, err)
+		}
+	} else {
+		routes := []client.Object{&networkingv1.Ingress{}}
+		if r.gatewayAPI {
+			routes = append(routes, newHTTPRoute())
+		}
+		for _, obj := range routes {
+			if err := r.deleteOwned(ctx, darkroom, obj); err != nil {
+				return ctrl.Result{}, r.recordFailure(darkroom, reasonDeleteRouteFailed, err)
+			}
+		}
+		if wait := r.DrainPeriod - time.Since(darkroom.DeletionTimestamp.Time); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+		for _, obj := range []client.Object{
+			&autoscalingv2beta2.HorizontalPodAutoscaler{},
+			&policyv1beta1.PodDisruptionBudget{},
+			&appsv1.Deployment{},
+		} {
+			if err := r.deleteOwned(ctx, darkroom, obj); err != nil {
+				return ctrl.Result{}, r.recordFailure(darkroom, reasonDeleteWorkloadFailed, err)
+			}
+		}
+	}
+
+	patch := client.MergeFrom(darkroom.DeepCopy())
+	controllerutil.RemoveFinalizer(darkroom, deploymentsv1alpha1.CleanupFinalizer)
+	if err := r.Patch(ctx, darkroom, patch); err != nil {
+		return ctrl.Result{}, r.recordFailure(darkroom, reasonRemoveFinalizerFailed, err)
+	}
 	return ctrl.Result{}, nil
 }
 
+// orphanOwned removes darkroom from the owner references of the objects generated for it,
+// so that the garbage collector leaves them in place
+func (r *DarkroomReconciler) orphanOwned(ctx context.Context, darkroom *deploymentsv1alpha1.Darkroom) error {
+	key := client.ObjectKeyFromObject(darkroom)
+	credentials := client.ObjectKey{Namespace: key.Namespace, Name: fmt.Sprintf("%s-credentials", key.Name)}
+	type ownedObject struct {
+		key client.ObjectKey
+		obj client.Object
+	}
+	owned := []ownedObject{
+		{key, &corev1.ConfigMap{}},
+		{credentials, &corev1.Secret{}},
+		{key, &appsv1.Deployment{}},
+		{key, &corev1.Service{}},
+		{key, &networkingv1.Ingress{}},
+		{key, &networkingv1.NetworkPolicy{}},
+		{key, &autoscalingv2beta2.HorizontalPodAutoscaler{}},
+		{key, &policyv1beta1.PodDisruptionBudget{}},
+	}
+	if r.gatewayAPI {
+		owned = append(owned, ownedObject{key, newHTTPRoute()})
+	}
+	if r.certManager {
+		owned = append(owned, ownedObject{key, newCertificate()})
+	}
+	if r.prometheusOperator {
+		owned = append(owned, ownedObject{key, newServiceMonitor()})
+	}
+
+	var errs []error
+	for _, o := range owned {
+		if err := r.Get(ctx, o.key, o.obj); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if !metav1.IsControlledBy(o.obj, darkroom) {
+			continue
+		}
+		patch := client.MergeFrom(o.obj.DeepCopyObject().(client.Object))
+		var refs []metav1.OwnerReference
+		for _, ref := range o.obj.GetOwnerReferences() {
+			if ref.UID != darkroom.UID {
+				refs = append(refs, ref)
+			}
+		}
+		o.obj.SetOwnerReferences(refs)
+		if err := r.Patch(ctx, o.obj, patch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// apply server-side applies obj, unless building it already failed with buildErr.
+// Failures are recorded as a warning Event on darkroom, creations and changes of obj as a normal one.
+// Changes reverting fields that were edited outside of the reconciler are counted as drift corrections.
+func (r *DarkroomReconciler) apply(ctx context.Context, darkroom *deploymentsv1alpha1.Darkroom, obj client.Object, buildErr error) error {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	err := buildErr
+	var getErr error
+	existing := obj.DeepCopyObject().(client.Object)
+	if err == nil {
+		getErr = r.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+		err = r.Patch(ctx, obj, client.Apply, applyOptions...)
+	}
+	if err != nil {
+		applyErrors.WithLabelValues(darkroom.Namespace, kind).Inc()
+		return r.recordFailure(darkroom, fmt.Sprintf("Apply%sFailed", kind), fmt.Errorf("unable to apply %s: %w", kind, err))
+	}
+	switch {
+	case apierrors.IsNotFound(getErr):
+		r.Recorder.Eventf(darkroom, corev1.EventTypeNormal, reasonCreated, "Created %s %s", kind, obj.GetName())
+	case getErr == nil && existing.GetResourceVersion() != obj.GetResourceVersion():
+		r.Recorder.Eventf(darkroom, corev1.EventTypeNormal, reasonUpdated, "Updated %s %s", kind, obj.GetName())
+		r.recordDrift(darkroom, existing, obj)
+	}
+	return nil
+}
+
+// recordDrift counts and records the fields of live that were changed outside of the reconciler and reverted
+// by applying obj
+func (r *DarkroomReconciler) recordDrift(darkroom *deploymentsv1alpha1.Darkroom, live, obj client.Object) {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	fields, err := driftedFields(live, obj)
+	if err != nil {
+		r.Log.Error(err, "unable to compare managed fields", "kind", kind, "name", obj.GetName())
+		return
+	}
+	if len(fields) == 0 {
+		return
+	}
+	driftCorrections.WithLabelValues(kind).Inc()
+	r.Recorder.Eventf(darkroom, corev1.EventTypeWarning, reasonDriftCorrected,
+		"Reverted changes to %s %s made outside of the operator: %s", kind, obj.GetName(), strings.Join(fields, ", "))
+}
+
+// reconcileAutoscaler applies the HorizontalPodAutoscaler of darkroom while spec.autoscaling is set and deletes it otherwise
+func (r *DarkroomReconciler) reconcileAutoscaler(ctx context.Context, darkroom *deploymentsv1alpha1.Darkroom) error {
+	if darkroom.Spec.Autoscaling == nil {
+		if err := r.deleteOwned(ctx, darkroom, &autoscalingv2beta2.HorizontalPodAutoscaler{}); err != nil {
+			return r.recordFailure(darkroom, reasonDeleteHorizontalPodAutoscalerFailed, err)
+		}
+		return nil
+	}
+	hpa, err := r.desiredHorizontalPodAutoscaler(*darkroom)
+	return r.apply(ctx, darkroom, &hpa, err)
+}
+
+// reconcileDisruptionBudget applies the PodDisruptionBudget of darkroom while more than one replica is requested
+// and deletes it otherwise, or when spec.disruptionBudget disables it
+func (r *DarkroomReconciler) reconcileDisruptionBudget(ctx context.Context, darkroom *deploymentsv1alpha1.Darkroom) error {
+	if b := darkroom.Spec.DisruptionBudget; (b != nil && b.Disabled) || maxReplicas(*darkroom) <= 1 {
+		if err := r.deleteOwned(ctx, darkroom, &policyv1beta1.PodDisruptionBudget{}); err != nil {
+			return r.recordFailure(darkroom, reasonDeletePodDisruptionBudgetFailed, err)
+		}
+		return nil
+	}
+	pdb, err := r.desiredPodDisruptionBudget(*darkroom)
+	return r.apply(ctx, darkroom, &pdb, err)
+}
+
+// reconcileNetworkPolicy applies the NetworkPolicy of darkroom while spec.networkPolicy is set and deletes it otherwise
+func (r *DarkroomReconciler) reconcileNetworkPolicy(ctx context.Context, darkroom *deploymentsv1alpha1.Darkroom) error {
+	if darkroom.Spec.NetworkPolicy == nil {
+		if err := r.deleteOwned(ctx, darkroom, &networkingv1.NetworkPolicy{}); err != nil {
+			return r.recordFailure(darkroom, reasonDeleteNetworkPolicyFailed, err)
+		}
+		return nil
+	}
+	policy, err := r.desiredNetworkPolicy(*darkroom)
+	return r.apply(ctx, darkroom, &policy, err)
+}
+
+// reconcileServiceMonitor applies the ServiceMonitor of darkroom while spec.serviceMonitor is set and deletes it
+// otherwise. Without the Prometheus Operator installed no ServiceMonitor is generated.
+func (r *DarkroomReconciler) reconcileServiceMonitor(ctx context.Context, darkroom *deploymentsv1alpha1.Darkroom) error {
+	if !r.prometheusOperator {
+		return nil
+	}
+	if darkroom.Spec.ServiceMonitor == nil {
+		if err := r.deleteOwned(ctx, darkroom, newServiceMonitor()); err != nil {
+			return r.recordFailure(darkroom, reasonDeleteServiceMonitorFailed, err)
+		}
+		return nil
+	}
+	sm, err := r.desiredServiceMonitor(*darkroom)
+	return r.apply(ctx, darkroom, &sm, err)
+}
+
+// reconcileCertificate requests a Certificate for the Domains of darkroom when spec.tls is set and returns it
+// as last seen in the cluster. Without cert-manager installed no Certificate is requested.
+func (r *DarkroomReconciler) reconcileCertificate(ctx context.Context, darkroom *deploymentsv1alpha1.Darkroom) (*unstructured.Unstructured, error) {
+	if !r.certManager {
+		return nil, nil
+	}
+	if darkroom.Spec.TLS == nil {
+		if err := r.deleteOwned(ctx, darkroom, newCertificate()); err != nil {
+			return nil, r.recordFailure(darkroom, reasonDeleteCertificateFailed, err)
+		}
+		return nil, nil
+	}
+	cert, err := r.desiredCertificate(*darkroom)
+	if err := r.apply(ctx, darkroom, &cert, err); err != nil {
+		return nil, err
+	}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(darkroom), &cert); err != nil {
+		return nil, r.recordFailure(darkroom, reasonGetCertificateFailed, err)
+	}
+	return &cert, nil
+}
+
+// reconcileRoute routes the Domains of darkroom to service through an HTTPRoute when a Gateway is referenced
+// and the Gateway API is installed, or through an Ingress otherwise. Falling back to an Ingress for a referenced
+// Gateway is recorded as a warning Event. It returns the hosts that were admitted.
+func (r *DarkroomReconciler) reconcileRoute(ctx context.Context, darkroom *deploymentsv1alpha1.Darkroom, service corev1.Service) ([]string, error) {
+	key := client.ObjectKeyFromObject(darkroom)
+	if darkroom.Spec.Ingress != nil && darkroom.Spec.Ingress.GatewayRef != nil && r.gatewayAPI {
+		route, err := r.desiredHTTPRoute(*darkroom, service)
+		if err := r.apply(ctx, darkroom, &route, err); err != nil {
+			return nil, err
+		}
+		if err := r.deleteOwned(ctx, darkroom, &networkingv1.Ingress{}); err != nil {
+			return nil, r.recordFailure(darkroom, reasonDeleteIngressFailed, err)
+		}
+		if err := r.Get(ctx, key, &route); err != nil {
+			return nil, r.recordFailure(darkroom, reasonGetRouteFailed, err)
+		}
+		return admittedRouteHosts(&route), nil
+	}
+
+	if darkroom.Spec.Ingress != nil && darkroom.Spec.Ingress.GatewayRef != nil {
+		r.Recorder.Event(darkroom, corev1.EventTypeWarning, reasonGatewayAPIUnavailable,
+			"spec.ingress.gatewayRef is set but the Gateway API CRDs are not installed, routing through an Ingress instead")
+	}
+	ing, err := r.desiredIngress(*darkroom, service)
+	if err := r.apply(ctx, darkroom, &ing, err); err != nil {
+		return nil, err
+	}
+	if r.gatewayAPI {
+		if err := r.deleteOwned(ctx, darkroom, newHTTPRoute()); err != nil {
+			return nil, r.recordFailure(darkroom, reasonDeleteHTTPRouteFailed, err)
+		}
+	}
+	if err := r.Get(ctx, key, &ing); err != nil {
+		return nil, r.recordFailure(darkroom, reasonGetRouteFailed, err)
+	}
+	return admittedIngressHosts(&ing), nil
+}
+
+// deleteOwned deletes the object of darkroom's name and namespace into which obj is read, if darkroom controls it
+func (r *DarkroomReconciler) deleteOwned(ctx context.Context, darkroom *deploymentsv1alpha1.Darkroom, obj client.Object) error {
+	if err := r.Get(ctx, client.ObjectKeyFromObject(darkroom), obj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(obj, darkroom) {
+		return nil
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, obj))
+}
+
+// recordFailure records err as a warning Event on darkroom and returns it tagged with reason
+func (r *DarkroomReconciler) recordFailure(darkroom *deploymentsv1alpha1.Darkroom, reason string, err error) error {
+	r.Recorder.Event(darkroom, corev1.EventTypeWarning, reason, err.Error())
+	return &reconcileError{reason: reason, err: err}
+}
+
+// recordTransitions records the status changes from old to darkroom that need the attention of users as Events:
+// resuming a paused reconciliation, handling a requested reconcile, the completion of a rollout and sources that
+// stopped validating
+func (r *DarkroomReconciler) recordTransitions(old, darkroom *deploymentsv1alpha1.Darkroom) {
+	status := darkroom.Status
+	if meta.IsStatusConditionTrue(old.Status.Conditions, deploymentsv1alpha1.PausedCondition) {
+		r.Recorder.Event(darkroom, corev1.EventTypeNormal, reasonResumed, "Reconciliation is resumed")
+	}
+	if at := status.LastHandledReconcileAt; at != "" && at != old.Status.LastHandledReconcileAt {
+		r.Recorder.Eventf(darkroom, corev1.EventTypeNormal, reasonReconcileRequested, "Reconciled as requested at %s", at)
+	}
+	if status.DeployState == deploymentsv1alpha1.Deployed && old.Status.DeployState != deploymentsv1alpha1.Deployed {
+		r.Recorder.Eventf(darkroom, corev1.EventTypeNormal, reasonRolloutComplete,
+			"Rolled out version %s to %d replicas", darkroom.Spec.Version, status.AvailableReplicas)
+	}
+	if meta.IsStatusConditionFalse(status.Conditions, deploymentsv1alpha1.SourceReachableCondition) &&
+		!meta.IsStatusConditionFalse(old.Status.Conditions, deploymentsv1alpha1.SourceReachableCondition) {
+		r.Recorder.Event(darkroom, corev1.EventTypeWarning, reasonDeploymentHeld,
+			"ConfigMap and Deployment are not updated until the Secrets of spec.source can be read")
+	}
+	wasValid := make(map[string]bool, len(old.Status.Sources))
+	for _, s := range old.Status.Sources {
+		wasValid[s.Path] = s.Valid
+	}
+	for _, s := range status.Sources {
+		if valid, ok := wasValid[s.Path]; !s.Valid && (valid || !ok) {
+			r.Recorder.Eventf(darkroom, corev1.EventTypeWarning, reasonSourceMisconfigured, "%s: %s", s.Path, s.Message)
+		}
+	}
+}
+
 func (r *DarkroomReconciler) SetupControllerWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&deploymentsv1alpha1.Darkroom{}).
+	r.gatewayAPI = hasKind(mgr, httpRouteGVK)
+	r.certManager = hasKind(mgr, certificateGVK)
+	if !r.certManager {
+		r.Log.Info("cert-manager is not installed, spec.tls will not be served")
+	}
+	r.prometheusOperator = hasKind(mgr, serviceMonitorGVK)
+	if !r.prometheusOperator {
+		r.Log.Info("the Prometheus Operator is not installed, spec.serviceMonitor will be ignored")
+	}
+	if err := registerFleetCollector(mgr.GetClient()); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &deploymentsv1alpha1.Darkroom{}, secretNamesIndex,
+		func(obj client.Object) []string {
+			return referencedSecretNames(*obj.(*deploymentsv1alpha1.Darkroom))
+		}); err != nil {
+		return err
+	}
+
+	// status updates are left out, annotations are kept so that pausing and requesting a reconcile take effect
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&deploymentsv1alpha1.Darkroom{}, builder.WithPredicates(
+			predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}),
+		)).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.Service{}).
-		Complete(r)
+		Owns(&networkingv1.Ingress{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		Owns(&autoscalingv2beta2.HorizontalPodAutoscaler{}).
+		Owns(&policyv1beta1.PodDisruptionBudget{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.darkroomsForSecret))
+	if r.gatewayAPI {
+		b = b.Owns(newHTTPRoute())
+	}
+	if r.certManager {
+		b = b.Owns(newCertificate())
+	}
+	if r.prometheusOperator {
+		b = b.Owns(newServiceMonitor())
+	}
+	return b.Complete(r)
+}
+
+// hasKind reports whether the API server of mgr serves gvk, e.g. whether an optional CRD is installed
+func hasKind(mgr ctrl.Manager, gvk schema.GroupVersionKind) bool {
+	_, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	return err == nil
+}
+
+// credentialsSecrets returns the Secrets referenced by the source of darkroom. Inline credentials are first
+// applied to a Secret owned by darkroom, so they never end up in the Deployment.
+func (r *DarkroomReconciler) credentialsSecrets(ctx context.Context, darkroom *deploymentsv1alpha1.Darkroom) ([]*corev1.Secret, error) {
+	var secrets []*corev1.Secret
+	if darkroom.Spec.Source.HasInlineCredentials() {
+		secret, err := r.desiredSecret(*darkroom)
+		if err := r.apply(ctx, darkroom, &secret, err); err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, &secret)
+	}
+	referenced, err := r.referencedSecrets(ctx, darkroom, darkroom.Spec.Source)
+	return append(secrets, referenced...), err
+}
+
+// fallbackSecrets returns the Secrets referenced by the fallback sources of darkroom whose Secrets could all be read,
+// and the errors resolving them indexed like spec.fallbackSources
+func (r *DarkroomReconciler) fallbackSecrets(ctx context.Context, darkroom *deploymentsv1alpha1.Darkroom) ([]*corev1.Secret, []error) {
+	var secrets []*corev1.Secret
+	errs := make([]error, len(darkroom.Spec.FallbackSources))
+	for i, s := range darkroom.Spec.FallbackSources {
+		var referenced []*corev1.Secret
+		if referenced, errs[i] = r.referencedSecrets(ctx, darkroom, s); errs[i] == nil {
+			secrets = append(secrets, referenced...)
+		}
+	}
+	return secrets, errs
+}
+
+// withResolvedFallbackSources returns darkroom with only the fallback sources whose Secrets could be read,
+// fallbackErrs are the errors resolving them indexed like spec.fallbackSources
+func withResolvedFallbackSources(darkroom deploymentsv1alpha1.Darkroom, fallbackErrs []error) deploymentsv1alpha1.Darkroom {
+	resolved := *darkroom.DeepCopy()
+	resolved.Spec.FallbackSources = nil
+	for i, s := range darkroom.Spec.FallbackSources {
+		if fallbackErrs[i] == nil {
+			resolved.Spec.FallbackSources = append(resolved.Spec.FallbackSources, s)
+		}
+	}
+	return resolved
+}
+
+// referencedSecrets returns the Secrets referenced by source, stopping at the first one that can not be read
+func (r *DarkroomReconciler) referencedSecrets(ctx context.Context, darkroom *deploymentsv1alpha1.Darkroom, source deploymentsv1alpha1.Source) ([]*corev1.Secret, error) {
+	var secrets []*corev1.Secret
+	for _, name := range source.SecretNames() {
+		secret := &corev1.Secret{}
+		key := client.ObjectKey{Namespace: darkroom.Namespace, Name: name}
+		if err := r.Get(ctx, key, secret); err != nil {
+			return secrets, r.recordFailure(darkroom, reasonSourceUnresolved, err)
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
 }
 
-func (r *DarkroomReconciler) SetupWebhookWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewWebhookManagedBy(mgr).
-		For(&deploymentsv1alpha1.Darkroom{}).
-		Complete()
+// darkroomsForSecret maps a Secret to the darkrooms referencing it, so that credential rotations are rolled out.
+// The darkrooms are looked up by the secretNamesIndex, Secrets no darkroom references map to no requests.
+func (r *DarkroomReconciler) darkroomsForSecret(obj client.Object) []reconcile.Request {
+	var list deploymentsv1alpha1.DarkroomList
+	if err := r.List(context.Background(), &list, client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{secretNamesIndex: obj.GetName()}); err != nil {
+		r.Log.Error(err, "unable to list darkrooms for secret", "secret", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, d := range list.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: d.Namespace, Name: d.Name},
+		})
+	}
+	return requests
+}
+
+// referencedSecretNames returns the names of the Secrets the source and fallback sources of darkroom are read with,
+// including the Secret generated for inline credentials
+func referencedSecretNames(darkroom deploymentsv1alpha1.Darkroom) []string {
+	var names []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if ref := credentialsSecretRef(darkroom); ref != nil {
+		add(ref.Name)
+	}
+	for _, s := range append([]deploymentsv1alpha1.Source{darkroom.Spec.Source}, darkroom.Spec.FallbackSources...) {
+		for _, name := range s.SecretNames() {
+			add(name)
+		}
+	}
+	return names
 }
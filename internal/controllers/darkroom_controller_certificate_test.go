@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/gojekfarm/darkroom-operator/internal/runtime"
+	"github.com/gojekfarm/darkroom-operator/internal/testhelper"
+	deploymentsv1alpha1 "github.com/gojekfarm/darkroom-operator/pkg/api/v1alpha1"
+)
+
+// DarkroomCertificateSuite runs the reconciler against a cluster with the cert-manager CRDs installed
+type DarkroomCertificateSuite struct {
+	suite.Suite
+	testEnv    testhelper.Environment
+	client     client.Client
+	reconciler *DarkroomReconciler
+}
+
+func TestDarkroomCertificateSuite(t *testing.T) {
+	suite.Run(t, new(DarkroomCertificateSuite))
+}
+
+func (s *DarkroomCertificateSuite) SetupSuite() {
+	s.testEnv = testhelper.NewTestEnvironment("..", "..").
+		WithCRDPaths(filepath.Join("testdata", "crds"))
+	s.reconciler = &DarkroomReconciler{
+		Log:      s.testEnv.GetLogger().WithName("controllers").WithName("Darkroom"),
+		Scheme:   runtime.Scheme(),
+		Recorder: record.NewFakeRecorder(100),
+	}
+	s.testEnv.Add(s.reconciler)
+	s.NoError(s.testEnv.Start())
+
+	var err error
+	s.client, err = testhelper.NewClient(s.testEnv.GetConfig())
+	s.reconciler.Client = s.client
+	s.NoError(err)
+}
+
+func (s *DarkroomCertificateSuite) TestCertificate() {
+	ctx := context.Background()
+	d := &deploymentsv1alpha1.Darkroom{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "darkroom-tls",
+			Namespace: "default",
+		},
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Source: deploymentsv1alpha1.Source{
+				Type: deploymentsv1alpha1.WebFolder,
+				WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+					BaseURL: "https://example.com/assets/images",
+				},
+			},
+			Domains: []string{"tls.darkroom.net"},
+			TLS: &deploymentsv1alpha1.TLS{
+				IssuerRef: deploymentsv1alpha1.IssuerRef{Name: "letsencrypt", Kind: "ClusterIssuer"},
+			},
+		},
+	}
+	s.NoError(s.client.Create(ctx, d))
+	key := client.ObjectKey{Name: d.Name, Namespace: d.Namespace}
+
+	cert := newCertificate()
+	s.Eventually(func() bool {
+		return s.client.Get(ctx, key, cert) == nil
+	}, 10*time.Second, 250*time.Millisecond)
+	secretName, _, _ := unstructured.NestedString(cert.Object, "spec", "secretName")
+	s.Equal("darkroom-tls-tls", secretName)
+	dnsNames, _, _ := unstructured.NestedStringSlice(cert.Object, "spec", "dnsNames")
+	s.Equal(d.Spec.Domains, dnsNames)
+	issuer, _, _ := unstructured.NestedStringMap(cert.Object, "spec", "issuerRef")
+	s.Equal(map[string]string{"name": "letsencrypt", "kind": "ClusterIssuer", "group": "cert-manager.io"}, issuer)
+
+	ing := &networkingv1.Ingress{}
+	s.Eventually(func() bool {
+		return s.client.Get(ctx, key, ing) == nil
+	}, 10*time.Second, 250*time.Millisecond)
+	s.Equal([]networkingv1.IngressTLS{{Hosts: d.Spec.Domains, SecretName: "darkroom-tls-tls"}}, ing.Spec.TLS)
+
+	// cert-manager is not running in the test environment to issue the certificate
+	cert.Object["status"] = map[string]interface{}{
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "True", "message": "Certificate is up to date and has not expired"},
+		},
+	}
+	s.NoError(s.client.Status().Update(ctx, cert))
+
+	s.Eventually(func() bool {
+		desired := &deploymentsv1alpha1.Darkroom{}
+		if err := s.client.Get(ctx, key, desired); err != nil {
+			return false
+		}
+		return meta.IsStatusConditionTrue(desired.Status.Conditions, deploymentsv1alpha1.CertificateReadyCondition)
+	}, 10*time.Second, 250*time.Millisecond)
+}
+
+func (s *DarkroomCertificateSuite) TearDownSuite() {
+	s.NoError(s.testEnv.Stop())
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"bytes"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+)
+
+// driftedFields returns the fields that managers other than the reconciler had taken over in live and that applying
+// forced back into applied. Fields shared with other managers keep being shared, so only the conflicting ones that
+// changed hands are reported.
+func driftedFields(live, applied client.Object) ([]string, error) {
+	before, err := managedFieldsSet(live, false)
+	if err != nil {
+		return nil, err
+	}
+	after, err := managedFieldsSet(applied, false)
+	if err != nil {
+		return nil, err
+	}
+	owned, err := managedFieldsSet(applied, true)
+	if err != nil {
+		return nil, err
+	}
+	var fields []string
+	before.Difference(after).Intersection(owned).Leaves().Iterate(func(p fieldpath.Path) {
+		fields = append(fields, p.String())
+	})
+	return fields, nil
+}
+
+// managedFieldsSet returns the union of the fields managed by the reconciler in obj when owned is set,
+// and of the fields managed by everyone else otherwise
+func managedFieldsSet(obj client.Object, owned bool) (*fieldpath.Set, error) {
+	set := &fieldpath.Set{}
+	for _, entry := range obj.GetManagedFields() {
+		if (entry.Manager == fieldManager) != owned || entry.FieldsV1 == nil {
+			continue
+		}
+		fields := &fieldpath.Set{}
+		if err := fields.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+			return nil, err
+		}
+		set = set.Union(fields)
+	}
+	return set, nil
+}
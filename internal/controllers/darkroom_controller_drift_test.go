@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDriftedFields(t *testing.T) {
+	entry := func(manager string, op metav1.ManagedFieldsOperationType, fields string) metav1.ManagedFieldsEntry {
+		return metav1.ManagedFieldsEntry{
+			Manager:    manager,
+			Operation:  op,
+			APIVersion: "v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(fields)},
+		}
+	}
+	live := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ManagedFields: []metav1.ManagedFieldsEntry{
+		entry(fieldManager, metav1.ManagedFieldsOperationApply, `{"f:data":{"f:DEBUG":{},"f:PORT":{}}}`),
+		entry("helm", metav1.ManagedFieldsOperationApply, `{"f:data":{"f:PORT":{}}}`),
+		entry("kubectl-edit", metav1.ManagedFieldsOperationUpdate, `{"f:data":{"f:SOURCE_KIND":{}},"f:metadata":{"f:labels":{"f:team":{}}}}`),
+	}}}
+	applied := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ManagedFields: []metav1.ManagedFieldsEntry{
+		entry(fieldManager, metav1.ManagedFieldsOperationApply, `{"f:data":{"f:DEBUG":{},"f:PORT":{},"f:SOURCE_KIND":{}}}`),
+		entry("helm", metav1.ManagedFieldsOperationApply, `{"f:data":{"f:PORT":{}}}`),
+		entry("kubectl-edit", metav1.ManagedFieldsOperationUpdate, `{"f:metadata":{"f:labels":{"f:team":{}}}}`),
+	}}}
+
+	fields, err := driftedFields(live, applied)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".data.SOURCE_KIND"}, fields)
+
+	fields, err = driftedFields(applied, applied)
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+
+	live.ManagedFields = append(live.ManagedFields, entry("broken", metav1.ManagedFieldsOperationUpdate, `{"f:data":`))
+	_, err = driftedFields(live, applied)
+	assert.Error(t, err)
+}
@@ -1,12 +1,23 @@
 package controllers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
 
@@ -14,35 +25,277 @@ import (
 )
 
 func (r *DarkroomReconciler) desiredConfigMap(darkroom deploymentsv1alpha1.Darkroom) (corev1.ConfigMap, error) {
+	server, cb := &deploymentsv1alpha1.Server{}, &deploymentsv1alpha1.CircuitBreaker{}
+	if darkroom.Spec.Server != nil {
+		server = darkroom.Spec.Server.DeepCopy()
+	}
+	if darkroom.Spec.Source.CircuitBreaker != nil {
+		cb = darkroom.Spec.Source.CircuitBreaker.DeepCopy()
+	}
+	server.Default()
+	cb.Default()
+
 	cfg := corev1.ConfigMap{
 		TypeMeta: metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "ConfigMap"},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      darkroom.Name,
 			Namespace: darkroom.Namespace,
 		},
-		Data: map[string]string{
-			"DEBUG":          "false",
-			"LOG_LEVEL":      "info",
-			"SOURCE_KIND":    string(darkroom.Spec.Source.Type),
-			"SOURCE_BASEURL": darkroom.Spec.Source.BaseURL,
-			"PORT":           "3000",
-			"CACHE_TIME":     "31536000",
-			"SOURCE_HYSTRIX_COMMANDNAME": strings.ToUpper(
-				fmt.Sprintf("%s_ADAPTER", darkroom.Spec.Source.Type),
-			),
-			"SOURCE_HYSTRIX_TIMEOUT":                "5000",
-			"SOURCE_HYSTRIX_MAXCONCURRENTREQUESTS":  "100",
-			"SOURCE_HYSTRIX_REQUESTVOLUMETHRESHOLD": "10",
-			"SOURCE_HYSTRIX_SLEEPWINDOW":            "10",
-			"SOURCE_HYSTRIX_ERRORPERCENTTHRESHOLD":  "25",
-		},
+		Data: map[string]string{},
+	}
+	for k, v := range darkroom.Spec.ExtraEnv {
+		cfg.Data[k] = v
+	}
+	for k, v := range bucketEnv("SOURCE_", darkroom.Spec.Source) {
+		cfg.Data[k] = v
+	}
+	for k, v := range azureEnv("SOURCE_", darkroom.Spec.Source) {
+		cfg.Data[k] = v
+	}
+	for k, v := range fallbackEnv(darkroom) {
+		cfg.Data[k] = v
+	}
+	for k, v := range map[string]string{
+		"DEBUG":          strconv.FormatBool(server.Debug),
+		"LOG_LEVEL":      server.LogLevel,
+		"SOURCE_KIND":    string(darkroom.Spec.Source.Type),
+		"SOURCE_BASEURL": darkroom.Spec.Source.BaseURL,
+		"PORT":           strconv.Itoa(int(server.Port)),
+		"METRICS_SYSTEM": "prometheus",
+		"METRICS_PORT":   strconv.Itoa(int(server.MetricsPort)),
+		"CACHE_TIME":     strconv.FormatInt(*server.CacheTime, 10),
+		"SOURCE_HYSTRIX_COMMANDNAME": strings.ToUpper(
+			fmt.Sprintf("%s_ADAPTER", darkroom.Spec.Source.Type),
+		),
+		"SOURCE_HYSTRIX_TIMEOUT":                strconv.Itoa(int(cb.Timeout)),
+		"SOURCE_HYSTRIX_MAXCONCURRENTREQUESTS":  strconv.Itoa(int(cb.MaxConcurrentRequests)),
+		"SOURCE_HYSTRIX_REQUESTVOLUMETHRESHOLD": strconv.Itoa(int(cb.RequestVolumeThreshold)),
+		"SOURCE_HYSTRIX_SLEEPWINDOW":            strconv.Itoa(int(cb.SleepWindow)),
+		"SOURCE_HYSTRIX_ERRORPERCENTTHRESHOLD":  strconv.Itoa(int(cb.ErrorPercentThreshold)),
+	} {
+		cfg.Data[k] = v
 	}
 
 	err := ctrl.SetControllerReference(&darkroom, &cfg, r.Scheme)
 	return cfg, err
 }
 
-func (r *DarkroomReconciler) desiredDeployment(darkroom deploymentsv1alpha1.Darkroom, configMap corev1.ConfigMap) (appsv1.Deployment, error) {
+// bucketEnv configures darkroom to read from the bucket of source, with the keys prefixed by prefix.
+// Credentials are set by credentialsEnv.
+func bucketEnv(prefix string, source deploymentsv1alpha1.Source) map[string]string {
+	b := source.Bucket
+	if b == nil {
+		return nil
+	}
+	env := map[string]string{prefix + "BUCKET_NAME": b.Name}
+	if source.Type != deploymentsv1alpha1.S3 {
+		return env
+	}
+	if b.Region != "" {
+		env[prefix+"BUCKET_REGION"] = b.Region
+	}
+	if b.Endpoint != "" {
+		env[prefix+"BUCKET_ENDPOINT"] = b.Endpoint
+	}
+	env[prefix+"BUCKET_PATHSTYLE"] = strconv.FormatBool(b.PathStyle)
+	env[prefix+"BUCKET_INSECURESKIPVERIFY"] = strconv.FormatBool(b.InsecureSkipVerify)
+	return env
+}
+
+// azureEnv configures darkroom to read from the Azure container of source, with the keys prefixed by prefix.
+// The SAS token is set by credentialsEnv.
+func azureEnv(prefix string, source deploymentsv1alpha1.Source) map[string]string {
+	a := source.Azure
+	if a == nil || source.Type != deploymentsv1alpha1.AzureBlobStorage {
+		return nil
+	}
+	return map[string]string{
+		prefix + "AZURE_ACCOUNTNAME": a.AccountName,
+		prefix + "AZURE_CONTAINER":   a.Container,
+	}
+}
+
+// fallbackPrefix prefixes the settings of the i-th fallback source of a darkroom
+func fallbackPrefix(i int) string {
+	return fmt.Sprintf("SOURCE_FALLBACK_%d_", i)
+}
+
+// fallbackEnv configures darkroom to consult the fallback sources of darkroom, in order, when an image
+// is missing from the source
+func fallbackEnv(darkroom deploymentsv1alpha1.Darkroom) map[string]string {
+	if len(darkroom.Spec.FallbackSources) == 0 {
+		return nil
+	}
+	env := map[string]string{"SOURCE_FALLBACKS": strconv.Itoa(len(darkroom.Spec.FallbackSources))}
+	for i, s := range darkroom.Spec.FallbackSources {
+		prefix := fallbackPrefix(i)
+		env[prefix+"KIND"] = string(s.Type)
+		if s.BaseURL != "" {
+			env[prefix+"BASEURL"] = s.BaseURL
+		}
+		for k, v := range bucketEnv(prefix, s) {
+			env[k] = v
+		}
+		for k, v := range azureEnv(prefix, s) {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// configHashAnnotation carries the configHash of the ConfigMap and Secrets consumed by the pods on the pod template,
+// so that changing them rolls the pods
+const configHashAnnotation = "deployments.gojek.io/config-hash"
+
+// configHash is a content hash of configMap and secrets, independent of the order of their keys
+func configHash(configMap corev1.ConfigMap, secrets ...*corev1.Secret) string {
+	h := sha256.New()
+	write := func(kind string, data map[string][]byte) {
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(h, "%s/%s=%d:", kind, k, len(data[k]))
+			h.Write(data[k])
+		}
+	}
+	data := map[string][]byte{}
+	for k, v := range configMap.Data {
+		data[k] = []byte(v)
+	}
+	write("ConfigMap", data)
+	for _, secret := range secrets {
+		if secret != nil {
+			write("Secret/"+secret.Name, secret.Data)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// credentialsSecretRef resolves the Secret, and its keys, holding the source credentials of darkroom.
+// Inline credentials resolve to the Secret generated by desiredSecret.
+func credentialsSecretRef(darkroom deploymentsv1alpha1.Darkroom) *deploymentsv1alpha1.CredentialsSecretRef {
+	var ref *deploymentsv1alpha1.CredentialsSecretRef
+	switch {
+	case darkroom.Spec.Source.CredentialsSecretRef() != nil:
+		ref = darkroom.Spec.Source.CredentialsSecretRef().DeepCopy()
+	case darkroom.Spec.Source.HasInlineCredentials():
+		ref = &deploymentsv1alpha1.CredentialsSecretRef{Name: fmt.Sprintf("%s-credentials", darkroom.Name)}
+	default:
+		return nil
+	}
+	ref.Default()
+	return ref
+}
+
+// credentialsEnv sets the credentials of the source and fallback sources of darkroom from their Secrets
+func credentialsEnv(darkroom deploymentsv1alpha1.Darkroom) []corev1.EnvVar {
+	env := sourceCredentialsEnv("SOURCE_", darkroom.Spec.Source, credentialsSecretRef(darkroom))
+	env = append(env, webFolderEnv("SOURCE_", darkroom.Spec.Source)...)
+	for i, s := range darkroom.Spec.FallbackSources {
+		if ref := s.CredentialsSecretRef(); ref != nil {
+			ref = ref.DeepCopy()
+			ref.Default()
+			env = append(env, sourceCredentialsEnv(fallbackPrefix(i), s, ref)...)
+		}
+		env = append(env, webFolderEnv(fallbackPrefix(i), s)...)
+	}
+	return env
+}
+
+// webFolderEnv sets the headers and basic auth credentials of the WebFolder source from their Secrets,
+// with the names prefixed by prefix. The header X-Api-Key is set as <prefix>HEADER_X_API_KEY.
+func webFolderEnv(prefix string, source deploymentsv1alpha1.Source) []corev1.EnvVar {
+	if source.Type != deploymentsv1alpha1.WebFolder {
+		return nil
+	}
+	var env []corev1.EnvVar
+	for _, name := range source.HeaderNames() {
+		ref := source.Headers[name]
+		env = append(env, corev1.EnvVar{
+			Name:      prefix + "HEADER_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")),
+			ValueFrom: secretKeyRef(ref.Name, ref.Key),
+		})
+	}
+	if ref := source.BasicAuthSecretRef; ref != nil {
+		ref = ref.DeepCopy()
+		ref.Default()
+		env = append(env,
+			corev1.EnvVar{Name: prefix + "BASICAUTH_USERNAME", ValueFrom: secretKeyRef(ref.Name, ref.UsernameKey)},
+			corev1.EnvVar{Name: prefix + "BASICAUTH_PASSWORD", ValueFrom: secretKeyRef(ref.Name, ref.PasswordKey)},
+		)
+	}
+	return env
+}
+
+func secretKeyRef(name, key string) *corev1.EnvVarSource {
+	return &corev1.EnvVarSource{
+		SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			Key:                  key,
+		},
+	}
+}
+
+// sourceCredentialsEnv sets the credentials of source from the Secret referenced by ref, with the names prefixed by prefix
+func sourceCredentialsEnv(prefix string, source deploymentsv1alpha1.Source, ref *deploymentsv1alpha1.CredentialsSecretRef) []corev1.EnvVar {
+	if ref == nil {
+		return nil
+	}
+	switch source.Type {
+	case deploymentsv1alpha1.S3:
+		return []corev1.EnvVar{
+			{Name: prefix + "BUCKET_ACCESSKEY", ValueFrom: secretKeyRef(ref.Name, ref.AccessKeyKey)},
+			{Name: prefix + "BUCKET_SECRETKEY", ValueFrom: secretKeyRef(ref.Name, ref.SecretKeyKey)},
+		}
+	case deploymentsv1alpha1.GoogleCloudStorage:
+		return []corev1.EnvVar{
+			{Name: prefix + "BUCKET_CREDENTIALSJSON", ValueFrom: secretKeyRef(ref.Name, ref.CredentialsJsonKey)},
+		}
+	case deploymentsv1alpha1.AzureBlobStorage:
+		return []corev1.EnvVar{
+			{Name: prefix + "AZURE_SASTOKEN", ValueFrom: secretKeyRef(ref.Name, ref.SASTokenKey)},
+		}
+	}
+	return nil
+}
+
+func (r *DarkroomReconciler) desiredSecret(darkroom deploymentsv1alpha1.Darkroom) (corev1.Secret, error) {
+	ref := credentialsSecretRef(darkroom)
+	if ref == nil {
+		return corev1.Secret{}, fmt.Errorf("darkroom %s has no source credentials", darkroom.Name)
+	}
+	secret := corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ref.Name,
+			Namespace: darkroom.Namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{},
+	}
+	if b := darkroom.Spec.Source.Bucket; b != nil {
+		if b.AccessKey != "" {
+			secret.Data[ref.AccessKeyKey] = []byte(b.AccessKey)
+		}
+		if b.SecretKey != "" {
+			secret.Data[ref.SecretKeyKey] = []byte(b.SecretKey)
+		}
+		if b.CredentialsJson != "" {
+			secret.Data[ref.CredentialsJsonKey] = []byte(b.CredentialsJson)
+		}
+	}
+	if a := darkroom.Spec.Source.Azure; a != nil && a.SASToken != "" {
+		secret.Data[ref.SASTokenKey] = []byte(a.SASToken)
+	}
+
+	err := ctrl.SetControllerReference(&darkroom, &secret, r.Scheme)
+	return secret, err
+}
+
+func (r *DarkroomReconciler) desiredDeployment(darkroom deploymentsv1alpha1.Darkroom, configMap corev1.ConfigMap, credentials ...*corev1.Secret) (appsv1.Deployment, error) {
 	depl := appsv1.Deployment{
 		TypeMeta: metav1.TypeMeta{APIVersion: appsv1.SchemeGroupVersion.String(), Kind: "Deployment"},
 		ObjectMeta: metav1.ObjectMeta{
@@ -51,17 +304,17 @@ func (r *DarkroomReconciler) desiredDeployment(darkroom deploymentsv1alpha1.Dark
 		},
 		Spec: appsv1.DeploymentSpec{
 			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{"darkroom": darkroom.Name},
+				MatchLabels: map[string]string{deploymentsv1alpha1.SelectorLabel: darkroom.Name},
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{"darkroom": darkroom.Name},
+					Labels: map[string]string{deploymentsv1alpha1.SelectorLabel: darkroom.Name},
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
 						{
 							Name:  "darkroom",
-							Image: fmt.Sprintf("gojektech/darkroom:%s", darkroom.Spec.Version),
+							Image: darkroom.ImageReference(r.imageRepository()),
 							EnvFrom: []corev1.EnvFromSource{
 								{
 									ConfigMapRef: &corev1.ConfigMapEnvSource{
@@ -71,32 +324,213 @@ func (r *DarkroomReconciler) desiredDeployment(darkroom deploymentsv1alpha1.Dark
 									},
 								},
 							},
+							Env: credentialsEnv(darkroom),
 							Ports: []corev1.ContainerPort{
-								{ContainerPort: 3000, Name: "http", Protocol: "TCP"},
+								{ContainerPort: serverPort(darkroom), Name: "http", Protocol: "TCP"},
+								{ContainerPort: metricsPort(darkroom), Name: "metrics", Protocol: "TCP"},
 							},
 						},
 					},
 				},
 			},
+			Strategy: appsv1.DeploymentStrategy{
+				Type:          appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{MaxSurge: &defaultMaxSurge, MaxUnavailable: &defaultMaxUnavailable},
+			},
 		},
 	}
+	var probes deploymentsv1alpha1.Probes
+	if dp := darkroom.Spec.Deployment; dp != nil && dp.Probes != nil {
+		probes = *dp.Probes
+	}
+	container := &depl.Spec.Template.Spec.Containers[0]
+	container.LivenessProbe = desiredProbe(probes.Liveness, corev1.Probe{PeriodSeconds: 10, FailureThreshold: 3})
+	container.ReadinessProbe = desiredProbe(probes.Readiness, corev1.Probe{PeriodSeconds: 5, FailureThreshold: 3})
+	container.StartupProbe = desiredProbe(probes.Startup, corev1.Probe{PeriodSeconds: 5, FailureThreshold: 30})
+	if i := darkroom.Spec.Image; i != nil {
+		depl.Spec.Template.Spec.Containers[0].ImagePullPolicy = i.PullPolicy
+		depl.Spec.Template.Spec.ImagePullSecrets = i.ImagePullSecrets
+	}
+	if dp := darkroom.Spec.Deployment; dp != nil {
+		pod := &depl.Spec.Template
+		if darkroom.Spec.Autoscaling == nil {
+			depl.Spec.Replicas = dp.Replicas
+		}
+		pod.Spec.Containers[0].Resources = dp.Resources
+		pod.Spec.NodeSelector = dp.NodeSelector
+		pod.Spec.Tolerations = dp.Tolerations
+		pod.Spec.Affinity = dp.Affinity
+		pod.Spec.TopologySpreadConstraints = dp.TopologySpreadConstraints
+		pod.Spec.PriorityClassName = dp.PriorityClassName
+		for k, v := range dp.PodLabels {
+			if k != deploymentsv1alpha1.SelectorLabel {
+				pod.Labels[k] = v
+			}
+		}
+		for k, v := range dp.PodAnnotations {
+			setAnnotation(&pod.ObjectMeta, k, v)
+		}
+		if dp.RollingUpdate != nil {
+			depl.Spec.Strategy.RollingUpdate = dp.RollingUpdate
+		}
+	}
+	setAnnotation(&depl.Spec.Template.ObjectMeta, configHashAnnotation, configHash(configMap, credentials...))
 
 	err := ctrl.SetControllerReference(&darkroom, &depl, r.Scheme)
 	return depl, err
 }
 
+func (r *DarkroomReconciler) desiredHorizontalPodAutoscaler(darkroom deploymentsv1alpha1.Darkroom) (autoscalingv2beta2.HorizontalPodAutoscaler, error) {
+	a := darkroom.Spec.Autoscaling
+	hpa := autoscalingv2beta2.HorizontalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{APIVersion: autoscalingv2beta2.SchemeGroupVersion.String(), Kind: "HorizontalPodAutoscaler"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      darkroom.Name,
+			Namespace: darkroom.Namespace,
+		},
+		Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
+				APIVersion: appsv1.SchemeGroupVersion.String(),
+				Kind:       "Deployment",
+				Name:       darkroom.Name,
+			},
+			MinReplicas: a.MinReplicas,
+			MaxReplicas: a.MaxReplicas,
+		},
+	}
+	if a.TargetCPUUtilizationPercentage != nil {
+		hpa.Spec.Metrics = append(hpa.Spec.Metrics, resourceMetric(corev1.ResourceCPU, a.TargetCPUUtilizationPercentage))
+	}
+	if a.TargetMemoryUtilizationPercentage != nil {
+		hpa.Spec.Metrics = append(hpa.Spec.Metrics, resourceMetric(corev1.ResourceMemory, a.TargetMemoryUtilizationPercentage))
+	}
+	for _, m := range a.CustomMetrics {
+		value := m.TargetAverageValue
+		hpa.Spec.Metrics = append(hpa.Spec.Metrics, autoscalingv2beta2.MetricSpec{
+			Type: autoscalingv2beta2.PodsMetricSourceType,
+			Pods: &autoscalingv2beta2.PodsMetricSource{
+				Metric: autoscalingv2beta2.MetricIdentifier{Name: m.Name},
+				Target: autoscalingv2beta2.MetricTarget{
+					Type:         autoscalingv2beta2.AverageValueMetricType,
+					AverageValue: &value,
+				},
+			},
+		})
+	}
+
+	err := ctrl.SetControllerReference(&darkroom, &hpa, r.Scheme)
+	return hpa, err
+}
+
+// maxReplicas is the most pods darkroom is scaled to, by the autoscaler or spec.deployment.replicas
+func maxReplicas(darkroom deploymentsv1alpha1.Darkroom) int32 {
+	if a := darkroom.Spec.Autoscaling; a != nil {
+		return a.MaxReplicas
+	}
+	if dp := darkroom.Spec.Deployment; dp != nil && dp.Replicas != nil {
+		return *dp.Replicas
+	}
+	return 1
+}
+
+var defaultPDBMaxUnavailable = intstr.FromInt(1)
+
+// desiredPodDisruptionBudget renders policy/v1beta1, the PodDisruptionBudget version served by every
+// cluster the operator supports
+func (r *DarkroomReconciler) desiredPodDisruptionBudget(darkroom deploymentsv1alpha1.Darkroom) (policyv1beta1.PodDisruptionBudget, error) {
+	pdb := policyv1beta1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{APIVersion: policyv1beta1.SchemeGroupVersion.String(), Kind: "PodDisruptionBudget"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      darkroom.Name,
+			Namespace: darkroom.Namespace,
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{deploymentsv1alpha1.SelectorLabel: darkroom.Name},
+			},
+			MaxUnavailable: &defaultPDBMaxUnavailable,
+		},
+	}
+	if b := darkroom.Spec.DisruptionBudget; b != nil && (b.MinAvailable != nil || b.MaxUnavailable != nil) {
+		pdb.Spec.MinAvailable, pdb.Spec.MaxUnavailable = b.MinAvailable, b.MaxUnavailable
+	}
+
+	err := ctrl.SetControllerReference(&darkroom, &pdb, r.Scheme)
+	return pdb, err
+}
+
+func resourceMetric(name corev1.ResourceName, utilization *int32) autoscalingv2beta2.MetricSpec {
+	return autoscalingv2beta2.MetricSpec{
+		Type: autoscalingv2beta2.ResourceMetricSourceType,
+		Resource: &autoscalingv2beta2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2beta2.MetricTarget{
+				Type:               autoscalingv2beta2.UtilizationMetricType,
+				AverageUtilization: utilization,
+			},
+		},
+	}
+}
+
+// healthPath is the darkroom endpoint probed unless a probe of spec.deployment.probes sets a handler
+const healthPath = "/ping"
+
+var (
+	defaultMaxSurge       = intstr.FromString("25%")
+	defaultMaxUnavailable = intstr.FromInt(0)
+)
+
+// desiredProbe returns probe, falling back to defaults, with an HTTP handler on the darkroom health endpoint
+// unless it sets one
+func desiredProbe(probe *corev1.Probe, defaults corev1.Probe) *corev1.Probe {
+	if probe == nil {
+		probe = &defaults
+	} else {
+		probe = probe.DeepCopy()
+	}
+	if probe.Exec == nil && probe.HTTPGet == nil && probe.TCPSocket == nil {
+		probe.HTTPGet = &corev1.HTTPGetAction{Path: healthPath, Port: intstr.FromString("http")}
+	}
+	return probe
+}
+
+// serverPort is the port the darkroom container listens on
+func serverPort(darkroom deploymentsv1alpha1.Darkroom) int32 {
+	if s := darkroom.Spec.Server; s != nil && s.Port != 0 {
+		return s.Port
+	}
+	return deploymentsv1alpha1.DefaultPort
+}
+
+// metricsPort is the port the darkroom container serves its Prometheus metrics on
+func metricsPort(darkroom deploymentsv1alpha1.Darkroom) int32 {
+	if s := darkroom.Spec.Server; s != nil && s.MetricsPort != 0 {
+		return s.MetricsPort
+	}
+	return deploymentsv1alpha1.DefaultMetricsPort
+}
+
+func setAnnotation(obj *metav1.ObjectMeta, key, value string) {
+	if obj.Annotations == nil {
+		obj.Annotations = map[string]string{}
+	}
+	obj.Annotations[key] = value
+}
+
 func (r *DarkroomReconciler) desiredService(darkroom deploymentsv1alpha1.Darkroom) (corev1.Service, error) {
 	svc := corev1.Service{
 		TypeMeta: metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "Service"},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      darkroom.Name,
 			Namespace: darkroom.Namespace,
+			Labels:    map[string]string{deploymentsv1alpha1.SelectorLabel: darkroom.Name},
 		},
 		Spec: corev1.ServiceSpec{
 			Ports: []corev1.ServicePort{
 				{Name: "http", Port: 8080, Protocol: "TCP", TargetPort: intstr.FromString("http")},
+				{Name: "metrics", Port: metricsPort(darkroom), Protocol: "TCP", TargetPort: intstr.FromString("metrics")},
 			},
-			Selector: map[string]string{"darkroom": darkroom.Name},
+			Selector: map[string]string{deploymentsv1alpha1.SelectorLabel: darkroom.Name},
 			Type:     corev1.ServiceTypeClusterIP,
 		},
 	}
@@ -104,3 +538,274 @@ func (r *DarkroomReconciler) desiredService(darkroom deploymentsv1alpha1.Darkroo
 	err := ctrl.SetControllerReference(&darkroom, &svc, r.Scheme)
 	return svc, err
 }
+
+// certificateGVK is the cert-manager kind requested through spec.tls
+var certificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// httpRouteGVK is the Gateway API kind rendered instead of an Ingress when a Gateway is referenced
+var httpRouteGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+
+// serviceMonitorGVK is the Prometheus Operator kind generated through spec.serviceMonitor
+var serviceMonitorGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}
+
+func newServiceMonitor() *unstructured.Unstructured {
+	sm := &unstructured.Unstructured{}
+	sm.SetGroupVersionKind(serviceMonitorGVK)
+	return sm
+}
+
+// desiredServiceMonitor scrapes the metrics port of the Service of darkroom
+func (r *DarkroomReconciler) desiredServiceMonitor(darkroom deploymentsv1alpha1.Darkroom) (unstructured.Unstructured, error) {
+	endpoint := map[string]interface{}{"port": "metrics", "path": "/metrics"}
+	if interval := darkroom.Spec.ServiceMonitor.Interval; interval != "" {
+		endpoint["interval"] = interval
+	}
+	sm := *newServiceMonitor()
+	sm.SetName(darkroom.Name)
+	sm.SetNamespace(darkroom.Namespace)
+	sm.SetLabels(darkroom.Spec.ServiceMonitor.Labels)
+	sm.Object["spec"] = map[string]interface{}{
+		"endpoints": []interface{}{endpoint},
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{deploymentsv1alpha1.SelectorLabel: darkroom.Name},
+		},
+	}
+
+	err := ctrl.SetControllerReference(&darkroom, &sm, r.Scheme)
+	return sm, err
+}
+
+func (r *DarkroomReconciler) desiredNetworkPolicy(darkroom deploymentsv1alpha1.Darkroom) (networkingv1.NetworkPolicy, error) {
+	np := darkroom.Spec.NetworkPolicy
+	tcp, udp := corev1.ProtocolTCP, corev1.ProtocolUDP
+	http, dns := intstr.FromString("http"), intstr.FromInt(53)
+	policy := networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{APIVersion: networkingv1.SchemeGroupVersion.String(), Kind: "NetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      darkroom.Name,
+			Namespace: darkroom.Namespace,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{deploymentsv1alpha1.SelectorLabel: darkroom.Name},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					From: []networkingv1.NetworkPolicyPeer{
+						{NamespaceSelector: np.IngressNamespaceSelector, PodSelector: np.IngressPodSelector},
+					},
+					Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &http}},
+				},
+			},
+			Egress: append([]networkingv1.NetworkPolicyEgressRule{
+				{Ports: []networkingv1.NetworkPolicyPort{{Protocol: &udp, Port: &dns}, {Protocol: &tcp, Port: &dns}}},
+			}, originEgress(darkroom)...),
+		},
+	}
+
+	// the metrics port is only opened to the selected Prometheus, never to every pod
+	if darkroom.Spec.ServiceMonitor != nil && (np.PrometheusNamespaceSelector != nil || np.PrometheusPodSelector != nil) {
+		metrics := intstr.FromString("metrics")
+		policy.Spec.Ingress = append(policy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+			From: []networkingv1.NetworkPolicyPeer{
+				{NamespaceSelector: np.PrometheusNamespaceSelector, PodSelector: np.PrometheusPodSelector},
+			},
+			Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &metrics}},
+		})
+	}
+
+	err := ctrl.SetControllerReference(&darkroom, &policy, r.Scheme)
+	return policy, err
+}
+
+// originEgress allows the darkroom pods to reach the origins of the source and fallback sources, with one rule
+// per distinct set of addresses and port
+func originEgress(darkroom deploymentsv1alpha1.Darkroom) []networkingv1.NetworkPolicyEgressRule {
+	var rules []networkingv1.NetworkPolicyEgressRule
+	seen := map[string]bool{}
+	for _, s := range append([]deploymentsv1alpha1.Source{darkroom.Spec.Source}, darkroom.Spec.FallbackSources...) {
+		host, port := origin(s)
+		cidrs := darkroom.Spec.NetworkPolicy.EgressCIDRs
+		if ip := net.ParseIP(host); ip.To4() != nil {
+			cidrs = []string{ip.String() + "/32"}
+		} else if ip != nil {
+			cidrs = []string{ip.String() + "/128"}
+		}
+		key := fmt.Sprintf("%s:%d", strings.Join(cidrs, ","), port.IntValue())
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		tcp := corev1.ProtocolTCP
+		rule := networkingv1.NetworkPolicyEgressRule{Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &port}}}
+		for _, cidr := range cidrs {
+			rule.To = append(rule.To, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// origin returns the host and port darkroom reads the images of source from. The host is empty when it is
+// only known to the darkroom image, e.g. the regional endpoint of a bucket.
+func origin(source deploymentsv1alpha1.Source) (string, intstr.IntOrString) {
+	raw := source.BaseURL
+	if source.Type != deploymentsv1alpha1.WebFolder {
+		raw = ""
+		if b := source.Bucket; b != nil && source.Type == deploymentsv1alpha1.S3 {
+			raw = b.Endpoint
+		}
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", intstr.FromInt(443)
+	}
+	if p, err := strconv.Atoi(u.Port()); err == nil {
+		return u.Hostname(), intstr.FromInt(p)
+	}
+	if u.Scheme == "http" {
+		return u.Hostname(), intstr.FromInt(80)
+	}
+	return u.Hostname(), intstr.FromInt(443)
+}
+
+func newHTTPRoute() *unstructured.Unstructured {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(httpRouteGVK)
+	return route
+}
+
+// routePath is the path under which the Domains of darkroom are routed to its Service
+func routePath(darkroom deploymentsv1alpha1.Darkroom) string {
+	return "/" + strings.TrimPrefix(darkroom.Spec.PathPrefix, "/")
+}
+
+func (r *DarkroomReconciler) desiredIngress(darkroom deploymentsv1alpha1.Darkroom, service corev1.Service) (networkingv1.Ingress, error) {
+	pathType := networkingv1.PathTypePrefix
+	backend := networkingv1.IngressBackend{
+		Service: &networkingv1.IngressServiceBackend{
+			Name: service.Name,
+			Port: networkingv1.ServiceBackendPort{Name: "http"},
+		},
+	}
+	ing := networkingv1.Ingress{
+		TypeMeta: metav1.TypeMeta{APIVersion: networkingv1.SchemeGroupVersion.String(), Kind: "Ingress"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      darkroom.Name,
+			Namespace: darkroom.Namespace,
+		},
+	}
+	for _, host := range darkroom.Spec.Domains {
+		ing.Spec.Rules = append(ing.Spec.Rules, networkingv1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{
+						{Path: routePath(darkroom), PathType: &pathType, Backend: backend},
+					},
+				},
+			},
+		})
+	}
+	if i := darkroom.Spec.Ingress; i != nil {
+		ing.Annotations = i.Annotations
+		ing.Spec.IngressClassName = i.ClassName
+	}
+	if secretName := r.tlsSecretName(darkroom); secretName != "" {
+		ing.Spec.TLS = []networkingv1.IngressTLS{
+			{Hosts: darkroom.Spec.Domains, SecretName: secretName},
+		}
+	}
+
+	err := ctrl.SetControllerReference(&darkroom, &ing, r.Scheme)
+	return ing, err
+}
+
+func (r *DarkroomReconciler) desiredHTTPRoute(darkroom deploymentsv1alpha1.Darkroom, service corev1.Service) (unstructured.Unstructured, error) {
+	gw := darkroom.Spec.Ingress.GatewayRef
+	parentRef := map[string]interface{}{"name": gw.Name}
+	if gw.Namespace != "" {
+		parentRef["namespace"] = gw.Namespace
+	}
+	hostnames := make([]interface{}, 0, len(darkroom.Spec.Domains))
+	for _, host := range darkroom.Spec.Domains {
+		hostnames = append(hostnames, host)
+	}
+	route := *newHTTPRoute()
+	route.SetName(darkroom.Name)
+	route.SetNamespace(darkroom.Namespace)
+	route.SetAnnotations(darkroom.Spec.Ingress.Annotations)
+	route.Object["spec"] = map[string]interface{}{
+		"parentRefs": []interface{}{parentRef},
+		"hostnames":  hostnames,
+		"rules": []interface{}{
+			map[string]interface{}{
+				"matches": []interface{}{
+					map[string]interface{}{
+						"path": map[string]interface{}{"type": "PathPrefix", "value": routePath(darkroom)},
+					},
+				},
+				"backendRefs": []interface{}{
+					map[string]interface{}{"name": service.Name, "port": int64(service.Spec.Ports[0].Port)},
+				},
+			},
+		},
+	}
+
+	err := ctrl.SetControllerReference(&darkroom, &route, r.Scheme)
+	return route, err
+}
+
+func newCertificate() *unstructured.Unstructured {
+	cert := &unstructured.Unstructured{}
+	cert.SetGroupVersionKind(certificateGVK)
+	return cert
+}
+
+// imageRepository is the repository of the darkroom image unless spec.image.repository is set
+func (r *DarkroomReconciler) imageRepository() string {
+	if r.DefaultImageRepository != "" {
+		return r.DefaultImageRepository
+	}
+	return deploymentsv1alpha1.DefaultImageRepository
+}
+
+// tlsSecretName is the Secret holding the certificate served for the Domains of darkroom, if any.
+// The Secret of spec.tls is only used when cert-manager is installed to issue it.
+func (r *DarkroomReconciler) tlsSecretName(darkroom deploymentsv1alpha1.Darkroom) string {
+	if darkroom.Spec.TLS != nil && r.certManager {
+		tls := darkroom.Spec.TLS.DeepCopy()
+		tls.Default(darkroom.Name)
+		return tls.SecretName
+	}
+	if darkroom.Spec.Ingress != nil {
+		return darkroom.Spec.Ingress.TLSSecretName
+	}
+	return ""
+}
+
+func (r *DarkroomReconciler) desiredCertificate(darkroom deploymentsv1alpha1.Darkroom) (unstructured.Unstructured, error) {
+	tls := darkroom.Spec.TLS.DeepCopy()
+	tls.Default(darkroom.Name)
+	dnsNames := make([]interface{}, 0, len(darkroom.Spec.Domains))
+	for _, host := range darkroom.Spec.Domains {
+		dnsNames = append(dnsNames, host)
+	}
+	cert := *newCertificate()
+	cert.SetName(darkroom.Name)
+	cert.SetNamespace(darkroom.Namespace)
+	cert.Object["spec"] = map[string]interface{}{
+		"secretName": tls.SecretName,
+		"dnsNames":   dnsNames,
+		"issuerRef": map[string]interface{}{
+			"name":  tls.IssuerRef.Name,
+			"kind":  tls.IssuerRef.Kind,
+			"group": tls.IssuerRef.Group,
+		},
+	}
+
+	err := ctrl.SetControllerReference(&darkroom, &cert, r.Scheme)
+	return cert, err
+}
@@ -0,0 +1,615 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/gojekfarm/darkroom-operator/internal/runtime"
+	deploymentsv1alpha1 "github.com/gojekfarm/darkroom-operator/pkg/api/v1alpha1"
+)
+
+func TestDesiredDeployment(t *testing.T) {
+	r := &DarkroomReconciler{Scheme: runtime.Scheme()}
+	replicas := int32(3)
+	darkroom := deploymentsv1alpha1.Darkroom{
+		ObjectMeta: metav1.ObjectMeta{Name: "darkroom", Namespace: "default"},
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Version: "v1",
+			Deployment: &deploymentsv1alpha1.Deployment{
+				Replicas: &replicas,
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+				},
+				NodeSelector: map[string]string{"pool": "images"},
+				Tolerations: []corev1.Toleration{
+					{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "images", Effect: corev1.TaintEffectNoSchedule},
+				},
+				PriorityClassName: "high-priority",
+				PodLabels:         map[string]string{"team": "images", deploymentsv1alpha1.SelectorLabel: "other"},
+				PodAnnotations:    map[string]string{"prometheus.io/scrape": "true"},
+			},
+		},
+	}
+	credentials := &corev1.Secret{Data: map[string][]byte{"accessKey": []byte("some-key")}}
+
+	depl, err := r.desiredDeployment(darkroom, corev1.ConfigMap{}, credentials)
+	assert.NoError(t, err)
+
+	pod := depl.Spec.Template
+	assert.Equal(t, &replicas, depl.Spec.Replicas)
+	assert.Equal(t, darkroom.Spec.Deployment.Resources, pod.Spec.Containers[0].Resources)
+	assert.Equal(t, darkroom.Spec.Deployment.NodeSelector, pod.Spec.NodeSelector)
+	assert.Equal(t, darkroom.Spec.Deployment.Tolerations, pod.Spec.Tolerations)
+	assert.Equal(t, "high-priority", pod.Spec.PriorityClassName)
+	assert.Equal(t, map[string]string{"team": "images", deploymentsv1alpha1.SelectorLabel: "darkroom"}, pod.Labels)
+	assert.Equal(t, map[string]string{
+		"prometheus.io/scrape": "true",
+		configHashAnnotation:   configHash(corev1.ConfigMap{}, credentials),
+	}, pod.Annotations)
+	assert.Equal(t, map[string]string{deploymentsv1alpha1.SelectorLabel: "darkroom"}, depl.Spec.Selector.MatchLabels)
+}
+
+func TestDesiredDeploymentDefaults(t *testing.T) {
+	r := &DarkroomReconciler{Scheme: runtime.Scheme()}
+	darkroom := deploymentsv1alpha1.Darkroom{ObjectMeta: metav1.ObjectMeta{Name: "darkroom", Namespace: "default"}}
+
+	depl, err := r.desiredDeployment(darkroom, corev1.ConfigMap{}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, depl.Spec.Replicas)
+	assert.Equal(t, map[string]string{configHashAnnotation: configHash(corev1.ConfigMap{})}, depl.Spec.Template.Annotations)
+	assert.Empty(t, depl.Spec.Template.Spec.Containers[0].Resources)
+}
+
+func TestDesiredDeploymentWithImage(t *testing.T) {
+	r := &DarkroomReconciler{Scheme: runtime.Scheme()}
+	darkroom := deploymentsv1alpha1.Darkroom{
+		ObjectMeta: metav1.ObjectMeta{Name: "darkroom", Namespace: "default"},
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Version: "v1",
+			Image: &deploymentsv1alpha1.Image{
+				Repository:       "registry.internal:5000/gojektech/darkroom",
+				PullPolicy:       corev1.PullAlways,
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+			},
+		},
+	}
+
+	depl, err := r.desiredDeployment(darkroom, corev1.ConfigMap{})
+	assert.NoError(t, err)
+	pod := depl.Spec.Template.Spec
+	assert.Equal(t, "registry.internal:5000/gojektech/darkroom:v1", pod.Containers[0].Image)
+	assert.Equal(t, corev1.PullAlways, pod.Containers[0].ImagePullPolicy)
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "registry"}}, pod.ImagePullSecrets)
+
+	darkroom.Spec.Image = nil
+	depl, err = r.desiredDeployment(darkroom, corev1.ConfigMap{})
+	assert.NoError(t, err)
+	assert.Equal(t, "gojektech/darkroom:v1", depl.Spec.Template.Spec.Containers[0].Image)
+
+	r.DefaultImageRepository = "mirror/darkroom"
+	depl, err = r.desiredDeployment(darkroom, corev1.ConfigMap{})
+	assert.NoError(t, err)
+	assert.Equal(t, "mirror/darkroom:v1", depl.Spec.Template.Spec.Containers[0].Image)
+}
+
+func TestDesiredDeploymentProbes(t *testing.T) {
+	r := &DarkroomReconciler{Scheme: runtime.Scheme()}
+	darkroom := deploymentsv1alpha1.Darkroom{ObjectMeta: metav1.ObjectMeta{Name: "darkroom", Namespace: "default"}}
+
+	depl, err := r.desiredDeployment(darkroom, corev1.ConfigMap{})
+	assert.NoError(t, err)
+	health := corev1.Handler{HTTPGet: &corev1.HTTPGetAction{Path: "/ping", Port: intstr.FromString("http")}}
+	container := depl.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, &corev1.Probe{Handler: health, PeriodSeconds: 10, FailureThreshold: 3}, container.LivenessProbe)
+	assert.Equal(t, &corev1.Probe{Handler: health, PeriodSeconds: 5, FailureThreshold: 3}, container.ReadinessProbe)
+	assert.Equal(t, &corev1.Probe{Handler: health, PeriodSeconds: 5, FailureThreshold: 30}, container.StartupProbe)
+	assert.Equal(t, appsv1.RollingUpdateDeploymentStrategyType, depl.Spec.Strategy.Type)
+	assert.Equal(t, intstr.FromString("25%"), *depl.Spec.Strategy.RollingUpdate.MaxSurge)
+	assert.Equal(t, intstr.FromInt(0), *depl.Spec.Strategy.RollingUpdate.MaxUnavailable)
+
+	maxSurge, maxUnavailable := intstr.FromInt(2), intstr.FromString("50%")
+	darkroom.Spec.Deployment = &deploymentsv1alpha1.Deployment{
+		Probes: &deploymentsv1alpha1.Probes{
+			Liveness: &corev1.Probe{InitialDelaySeconds: 30},
+			Readiness: &corev1.Probe{
+				Handler: corev1.Handler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("http")}},
+			},
+		},
+		RollingUpdate: &appsv1.RollingUpdateDeployment{MaxSurge: &maxSurge, MaxUnavailable: &maxUnavailable},
+	}
+
+	depl, err = r.desiredDeployment(darkroom, corev1.ConfigMap{})
+	assert.NoError(t, err)
+	container = depl.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, &corev1.Probe{Handler: health, InitialDelaySeconds: 30}, container.LivenessProbe)
+	assert.Equal(t, darkroom.Spec.Deployment.Probes.Readiness, container.ReadinessProbe)
+	assert.Equal(t, &corev1.Probe{Handler: health, PeriodSeconds: 5, FailureThreshold: 30}, container.StartupProbe)
+	assert.Nil(t, darkroom.Spec.Deployment.Probes.Liveness.HTTPGet)
+	assert.Equal(t, darkroom.Spec.Deployment.RollingUpdate, depl.Spec.Strategy.RollingUpdate)
+}
+
+func TestDesiredDeploymentWithAutoscaling(t *testing.T) {
+	r := &DarkroomReconciler{Scheme: runtime.Scheme()}
+	replicas := int32(3)
+	darkroom := deploymentsv1alpha1.Darkroom{
+		ObjectMeta: metav1.ObjectMeta{Name: "darkroom", Namespace: "default"},
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Deployment:  &deploymentsv1alpha1.Deployment{Replicas: &replicas},
+			Autoscaling: &deploymentsv1alpha1.Autoscaling{MaxReplicas: 10},
+		},
+	}
+
+	depl, err := r.desiredDeployment(darkroom, corev1.ConfigMap{}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, depl.Spec.Replicas)
+}
+
+func TestDesiredHorizontalPodAutoscaler(t *testing.T) {
+	r := &DarkroomReconciler{Scheme: runtime.Scheme()}
+	minReplicas, cpu := int32(2), int32(70)
+	darkroom := deploymentsv1alpha1.Darkroom{
+		ObjectMeta: metav1.ObjectMeta{Name: "darkroom", Namespace: "default"},
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Autoscaling: &deploymentsv1alpha1.Autoscaling{
+				MinReplicas:                    &minReplicas,
+				MaxReplicas:                    10,
+				TargetCPUUtilizationPercentage: &cpu,
+				CustomMetrics: []deploymentsv1alpha1.CustomMetric{
+					{Name: "requests_per_second", TargetAverageValue: resource.MustParse("100")},
+				},
+			},
+		},
+	}
+
+	hpa, err := r.desiredHorizontalPodAutoscaler(darkroom)
+	assert.NoError(t, err)
+	assert.Equal(t, "Deployment", hpa.Spec.ScaleTargetRef.Kind)
+	assert.Equal(t, "darkroom", hpa.Spec.ScaleTargetRef.Name)
+	assert.Equal(t, &minReplicas, hpa.Spec.MinReplicas)
+	assert.Equal(t, int32(10), hpa.Spec.MaxReplicas)
+	assert.Len(t, hpa.Spec.Metrics, 2)
+	assert.Equal(t, corev1.ResourceCPU, hpa.Spec.Metrics[0].Resource.Name)
+	assert.Equal(t, &cpu, hpa.Spec.Metrics[0].Resource.Target.AverageUtilization)
+	assert.Equal(t, "requests_per_second", hpa.Spec.Metrics[1].Pods.Metric.Name)
+	assert.Equal(t, "100", hpa.Spec.Metrics[1].Pods.Target.AverageValue.String())
+	assert.True(t, metav1.IsControlledBy(&hpa, &darkroom))
+}
+
+func TestDesiredPodDisruptionBudget(t *testing.T) {
+	r := &DarkroomReconciler{Scheme: runtime.Scheme()}
+	darkroom := deploymentsv1alpha1.Darkroom{ObjectMeta: metav1.ObjectMeta{Name: "darkroom", Namespace: "default"}}
+
+	pdb, err := r.desiredPodDisruptionBudget(darkroom)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{deploymentsv1alpha1.SelectorLabel: "darkroom"}, pdb.Spec.Selector.MatchLabels)
+	assert.Nil(t, pdb.Spec.MinAvailable)
+	assert.Equal(t, intstr.FromInt(1), *pdb.Spec.MaxUnavailable)
+	assert.Len(t, pdb.OwnerReferences, 1)
+
+	minAvailable := intstr.FromString("50%")
+	darkroom.Spec.DisruptionBudget = &deploymentsv1alpha1.DisruptionBudget{MinAvailable: &minAvailable}
+	pdb, err = r.desiredPodDisruptionBudget(darkroom)
+	assert.NoError(t, err)
+	assert.Equal(t, &minAvailable, pdb.Spec.MinAvailable)
+	assert.Nil(t, pdb.Spec.MaxUnavailable)
+}
+
+func TestMaxReplicas(t *testing.T) {
+	replicas := int32(3)
+	assert.Equal(t, int32(1), maxReplicas(deploymentsv1alpha1.Darkroom{}))
+	assert.Equal(t, int32(3), maxReplicas(deploymentsv1alpha1.Darkroom{Spec: deploymentsv1alpha1.DarkroomSpec{
+		Deployment: &deploymentsv1alpha1.Deployment{Replicas: &replicas},
+	}}))
+	assert.Equal(t, int32(10), maxReplicas(deploymentsv1alpha1.Darkroom{Spec: deploymentsv1alpha1.DarkroomSpec{
+		Deployment:  &deploymentsv1alpha1.Deployment{Replicas: &replicas},
+		Autoscaling: &deploymentsv1alpha1.Autoscaling{MaxReplicas: 10},
+	}}))
+}
+
+func TestDesiredNetworkPolicy(t *testing.T) {
+	r := &DarkroomReconciler{Scheme: runtime.Scheme()}
+	ingressNamespace := &metav1.LabelSelector{MatchLabels: map[string]string{"name": "ingress-nginx"}}
+	darkroom := deploymentsv1alpha1.Darkroom{
+		ObjectMeta: metav1.ObjectMeta{Name: "darkroom", Namespace: "default"},
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Source: deploymentsv1alpha1.Source{
+				Type:          deploymentsv1alpha1.WebFolder,
+				WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{BaseURL: "http://10.1.2.3:8080/images"},
+			},
+			FallbackSources: []deploymentsv1alpha1.Source{
+				{Type: deploymentsv1alpha1.S3, Bucket: &deploymentsv1alpha1.Bucket{Name: "images"}},
+				{Type: deploymentsv1alpha1.GoogleCloudStorage, Bucket: &deploymentsv1alpha1.Bucket{Name: "images"}},
+			},
+			NetworkPolicy: &deploymentsv1alpha1.NetworkPolicy{
+				IngressNamespaceSelector: ingressNamespace,
+				EgressCIDRs:              []string{"52.0.0.0/8"},
+			},
+		},
+	}
+
+	policy, err := r.desiredNetworkPolicy(darkroom)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{deploymentsv1alpha1.SelectorLabel: "darkroom"}, policy.Spec.PodSelector.MatchLabels)
+	assert.Equal(t, []networkingv1.NetworkPolicyPeer{{NamespaceSelector: ingressNamespace}}, policy.Spec.Ingress[0].From)
+	assert.Equal(t, intstr.FromString("http"), *policy.Spec.Ingress[0].Ports[0].Port)
+
+	egress := policy.Spec.Egress
+	assert.Len(t, egress, 3)
+	assert.Empty(t, egress[0].To)
+	assert.Equal(t, intstr.FromInt(53), *egress[0].Ports[0].Port)
+	assert.Equal(t, []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.1.2.3/32"}}}, egress[1].To)
+	assert.Equal(t, intstr.FromInt(8080), *egress[1].Ports[0].Port)
+	assert.Equal(t, []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "52.0.0.0/8"}}}, egress[2].To)
+	assert.Equal(t, intstr.FromInt(443), *egress[2].Ports[0].Port)
+
+	assert.Len(t, policy.Spec.Ingress, 1)
+
+	darkroom.Spec.ServiceMonitor = &deploymentsv1alpha1.ServiceMonitor{}
+	policy, err = r.desiredNetworkPolicy(darkroom)
+	assert.NoError(t, err)
+	assert.Len(t, policy.Spec.Ingress, 1, "the metrics port is opened without a Prometheus selector")
+
+	monitoringNamespace := &metav1.LabelSelector{MatchLabels: map[string]string{"name": "monitoring"}}
+	prometheus := &metav1.LabelSelector{MatchLabels: map[string]string{"app.kubernetes.io/name": "prometheus"}}
+	darkroom.Spec.NetworkPolicy.PrometheusNamespaceSelector = monitoringNamespace
+	darkroom.Spec.NetworkPolicy.PrometheusPodSelector = prometheus
+	policy, err = r.desiredNetworkPolicy(darkroom)
+	assert.NoError(t, err)
+	assert.Len(t, policy.Spec.Ingress, 2)
+	assert.Equal(t, []networkingv1.NetworkPolicyPeer{{NamespaceSelector: monitoringNamespace, PodSelector: prometheus}}, policy.Spec.Ingress[1].From)
+	assert.Equal(t, intstr.FromString("metrics"), *policy.Spec.Ingress[1].Ports[0].Port)
+	assert.Equal(t, []networkingv1.NetworkPolicyPeer{{NamespaceSelector: ingressNamespace}}, policy.Spec.Ingress[0].From)
+}
+
+func TestOrigin(t *testing.T) {
+	testcases := []struct {
+		source deploymentsv1alpha1.Source
+		host   string
+		port   int
+	}{
+		{
+			source: deploymentsv1alpha1.Source{Type: deploymentsv1alpha1.WebFolder, WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{BaseURL: "https://example.com/images"}},
+			host:   "example.com",
+			port:   443,
+		},
+		{
+			source: deploymentsv1alpha1.Source{Type: deploymentsv1alpha1.WebFolder, WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{BaseURL: "http://[::1]/images"}},
+			host:   "::1",
+			port:   80,
+		},
+		{
+			source: deploymentsv1alpha1.Source{Type: deploymentsv1alpha1.S3, Bucket: &deploymentsv1alpha1.Bucket{Name: "images", Endpoint: "http://minio:9000"}},
+			host:   "minio",
+			port:   9000,
+		},
+		{
+			source: deploymentsv1alpha1.Source{Type: deploymentsv1alpha1.AzureBlobStorage, Azure: &deploymentsv1alpha1.AzureBlob{AccountName: "images"}},
+			port:   443,
+		},
+	}
+	for _, tc := range testcases {
+		host, port := origin(tc.source)
+		assert.Equal(t, tc.host, host)
+		assert.Equal(t, tc.port, port.IntValue())
+	}
+}
+
+func TestDesiredServiceMonitor(t *testing.T) {
+	r := &DarkroomReconciler{Scheme: runtime.Scheme()}
+	darkroom := deploymentsv1alpha1.Darkroom{
+		ObjectMeta: metav1.ObjectMeta{Name: "darkroom", Namespace: "default"},
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Server: &deploymentsv1alpha1.Server{MetricsPort: 9100},
+			ServiceMonitor: &deploymentsv1alpha1.ServiceMonitor{
+				Labels:   map[string]string{"release": "prometheus"},
+				Interval: "30s",
+			},
+		},
+	}
+
+	svc, err := r.desiredService(darkroom)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{deploymentsv1alpha1.SelectorLabel: "darkroom"}, svc.Labels)
+	assert.Equal(t, corev1.ServicePort{Name: "metrics", Port: 9100, Protocol: "TCP", TargetPort: intstr.FromString("metrics")}, svc.Spec.Ports[1])
+
+	depl, err := r.desiredDeployment(darkroom, corev1.ConfigMap{})
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.ContainerPort{ContainerPort: 9100, Name: "metrics", Protocol: "TCP"}, depl.Spec.Template.Spec.Containers[0].Ports[1])
+
+	sm, err := r.desiredServiceMonitor(darkroom)
+	assert.NoError(t, err)
+	assert.Equal(t, serviceMonitorGVK, sm.GroupVersionKind())
+	assert.Equal(t, map[string]string{"release": "prometheus"}, sm.GetLabels())
+	assert.Equal(t, map[string]interface{}{
+		"endpoints": []interface{}{map[string]interface{}{"port": "metrics", "path": "/metrics", "interval": "30s"}},
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{deploymentsv1alpha1.SelectorLabel: "darkroom"},
+		},
+	}, sm.Object["spec"])
+	assert.Len(t, sm.GetOwnerReferences(), 1)
+}
+
+func TestTLSSecretName(t *testing.T) {
+	darkroom := deploymentsv1alpha1.Darkroom{
+		ObjectMeta: metav1.ObjectMeta{Name: "darkroom"},
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Ingress: &deploymentsv1alpha1.Ingress{TLSSecretName: "wildcard-tls"},
+			TLS:     &deploymentsv1alpha1.TLS{IssuerRef: deploymentsv1alpha1.IssuerRef{Name: "letsencrypt"}},
+		},
+	}
+
+	r := &DarkroomReconciler{certManager: true}
+	assert.Equal(t, "darkroom-tls", r.tlsSecretName(darkroom))
+
+	r.certManager = false
+	assert.Equal(t, "wildcard-tls", r.tlsSecretName(darkroom))
+
+	darkroom.Spec.Ingress = nil
+	assert.Empty(t, r.tlsSecretName(darkroom))
+}
+
+func TestDesiredConfigMap(t *testing.T) {
+	r := &DarkroomReconciler{Scheme: runtime.Scheme()}
+	cacheTime := int64(600)
+	darkroom := deploymentsv1alpha1.Darkroom{
+		ObjectMeta: metav1.ObjectMeta{Name: "darkroom", Namespace: "default"},
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Source: deploymentsv1alpha1.Source{
+				Type:           deploymentsv1alpha1.WebFolder,
+				WebFolderMeta:  deploymentsv1alpha1.WebFolderMeta{BaseURL: "https://example.com/assets/images"},
+				CircuitBreaker: &deploymentsv1alpha1.CircuitBreaker{Timeout: 1000, ErrorPercentThreshold: 50},
+			},
+			Server: &deploymentsv1alpha1.Server{Debug: true, LogLevel: "debug", Port: 8000, CacheTime: &cacheTime},
+			ExtraEnv: map[string]string{
+				"SOURCE_PATHPREFIX": "/images",
+				"PORT":              "9000",
+			},
+		},
+	}
+
+	cfg, err := r.desiredConfigMap(darkroom)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"CACHE_TIME":                            "600",
+		"DEBUG":                                 "true",
+		"LOG_LEVEL":                             "debug",
+		"PORT":                                  "8000",
+		"METRICS_SYSTEM":                        "prometheus",
+		"METRICS_PORT":                          "9090",
+		"SOURCE_BASEURL":                        "https://example.com/assets/images",
+		"SOURCE_HYSTRIX_COMMANDNAME":            "WEBFOLDER_ADAPTER",
+		"SOURCE_HYSTRIX_ERRORPERCENTTHRESHOLD":  "50",
+		"SOURCE_HYSTRIX_MAXCONCURRENTREQUESTS":  "100",
+		"SOURCE_HYSTRIX_REQUESTVOLUMETHRESHOLD": "10",
+		"SOURCE_HYSTRIX_SLEEPWINDOW":            "10",
+		"SOURCE_HYSTRIX_TIMEOUT":                "1000",
+		"SOURCE_KIND":                           "WebFolder",
+		"SOURCE_PATHPREFIX":                     "/images",
+	}, cfg.Data)
+
+	depl, err := r.desiredDeployment(darkroom, cfg, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(8000), depl.Spec.Template.Spec.Containers[0].Ports[0].ContainerPort)
+}
+
+func TestConfigHash(t *testing.T) {
+	cfg := corev1.ConfigMap{Data: map[string]string{"LOG_LEVEL": "info", "PORT": "3000"}}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "credentials", ResourceVersion: "1"},
+		Data:       map[string][]byte{"accessKey": []byte("some-key")},
+	}
+	hash := configHash(cfg, secret)
+
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, configHash(*cfg.DeepCopy(), secret.DeepCopy()))
+
+	resynced := secret.DeepCopy()
+	resynced.ResourceVersion = "2"
+	assert.Equal(t, hash, configHash(cfg, resynced), "only the content is hashed")
+
+	tuned := cfg.DeepCopy()
+	tuned.Data["LOG_LEVEL"] = "debug"
+	assert.NotEqual(t, hash, configHash(*tuned, secret))
+
+	rotated := secret.DeepCopy()
+	rotated.Data["accessKey"] = []byte("other-key")
+	assert.NotEqual(t, hash, configHash(cfg, rotated))
+	assert.NotEqual(t, hash, configHash(cfg))
+}
+
+func TestBucketEnv(t *testing.T) {
+	testcases := []struct {
+		name   string
+		source deploymentsv1alpha1.Source
+		want   map[string]string
+	}{
+		{
+			name:   "WebFolder",
+			source: deploymentsv1alpha1.Source{Type: deploymentsv1alpha1.WebFolder},
+		},
+		{
+			name: "S3",
+			source: deploymentsv1alpha1.Source{
+				Type:   deploymentsv1alpha1.S3,
+				Bucket: &deploymentsv1alpha1.Bucket{Name: "images", Region: "eu-west-1"},
+			},
+			want: map[string]string{
+				"SOURCE_BUCKET_NAME":               "images",
+				"SOURCE_BUCKET_REGION":             "eu-west-1",
+				"SOURCE_BUCKET_PATHSTYLE":          "false",
+				"SOURCE_BUCKET_INSECURESKIPVERIFY": "false",
+			},
+		},
+		{
+			name: "MinIO",
+			source: deploymentsv1alpha1.Source{
+				Type: deploymentsv1alpha1.S3,
+				Bucket: &deploymentsv1alpha1.Bucket{
+					Name:               "images",
+					Endpoint:           "https://minio.storage.svc:9000",
+					PathStyle:          true,
+					InsecureSkipVerify: true,
+				},
+			},
+			want: map[string]string{
+				"SOURCE_BUCKET_NAME":               "images",
+				"SOURCE_BUCKET_ENDPOINT":           "https://minio.storage.svc:9000",
+				"SOURCE_BUCKET_PATHSTYLE":          "true",
+				"SOURCE_BUCKET_INSECURESKIPVERIFY": "true",
+			},
+		},
+		{
+			name: "GoogleCloudStorage",
+			source: deploymentsv1alpha1.Source{
+				Type:   deploymentsv1alpha1.GoogleCloudStorage,
+				Bucket: &deploymentsv1alpha1.Bucket{Name: "images"},
+			},
+			want: map[string]string{"SOURCE_BUCKET_NAME": "images"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, bucketEnv("SOURCE_", tc.source))
+		})
+	}
+}
+
+func TestAzureBlobStorage(t *testing.T) {
+	r := &DarkroomReconciler{Scheme: runtime.Scheme()}
+	darkroom := deploymentsv1alpha1.Darkroom{
+		ObjectMeta: metav1.ObjectMeta{Name: "darkroom", Namespace: "default"},
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Source: deploymentsv1alpha1.Source{
+				Type: deploymentsv1alpha1.AzureBlobStorage,
+				Azure: &deploymentsv1alpha1.AzureBlob{
+					AccountName: "darkroom",
+					Container:   "images",
+					SASToken:    "sv=2020&sig=abc",
+				},
+			},
+		},
+	}
+
+	cfg, err := r.desiredConfigMap(darkroom)
+	assert.NoError(t, err)
+	assert.Equal(t, "AzureBlobStorage", cfg.Data["SOURCE_KIND"])
+	assert.Equal(t, "darkroom", cfg.Data["SOURCE_AZURE_ACCOUNTNAME"])
+	assert.Equal(t, "images", cfg.Data["SOURCE_AZURE_CONTAINER"])
+	assert.NotContains(t, cfg.Data, "SOURCE_BUCKET_NAME")
+
+	secret, err := r.desiredSecret(darkroom)
+	assert.NoError(t, err)
+	assert.Equal(t, "darkroom-credentials", secret.Name)
+	assert.Equal(t, map[string][]byte{"sasToken": []byte("sv=2020&sig=abc")}, secret.Data)
+
+	env := credentialsEnv(darkroom)
+	assert.Len(t, env, 1)
+	assert.Equal(t, "SOURCE_AZURE_SASTOKEN", env[0].Name)
+	assert.Equal(t, "darkroom-credentials", env[0].ValueFrom.SecretKeyRef.Name)
+	assert.Equal(t, "sasToken", env[0].ValueFrom.SecretKeyRef.Key)
+	assert.Equal(t, []string{"darkroom-credentials"}, referencedSecretNames(darkroom))
+
+	darkroom.Spec.Source.Azure.SASToken = ""
+	darkroom.Spec.Source.Azure.CredentialsSecretRef = &deploymentsv1alpha1.CredentialsSecretRef{Name: "azure", SASTokenKey: "token"}
+	env = credentialsEnv(darkroom)
+	assert.Equal(t, "azure", env[0].ValueFrom.SecretKeyRef.Name)
+	assert.Equal(t, "token", env[0].ValueFrom.SecretKeyRef.Key)
+}
+
+func TestFallbackSources(t *testing.T) {
+	r := &DarkroomReconciler{Scheme: runtime.Scheme()}
+	darkroom := deploymentsv1alpha1.Darkroom{
+		ObjectMeta: metav1.ObjectMeta{Name: "darkroom", Namespace: "default"},
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Source: deploymentsv1alpha1.Source{
+				Type:          deploymentsv1alpha1.WebFolder,
+				WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{BaseURL: "https://example.com/images"},
+			},
+			FallbackSources: []deploymentsv1alpha1.Source{
+				{
+					Type: deploymentsv1alpha1.S3,
+					Bucket: &deploymentsv1alpha1.Bucket{
+						Name:                 "images",
+						Region:               "eu-west-1",
+						CredentialsSecretRef: &deploymentsv1alpha1.CredentialsSecretRef{Name: "s3"},
+					},
+				},
+				{
+					Type:          deploymentsv1alpha1.WebFolder,
+					WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{BaseURL: "https://legacy.example.com/images"},
+				},
+			},
+		},
+	}
+
+	cfg, err := r.desiredConfigMap(darkroom)
+	assert.NoError(t, err)
+	for k, v := range map[string]string{
+		"SOURCE_KIND":                                 "WebFolder",
+		"SOURCE_BASEURL":                              "https://example.com/images",
+		"SOURCE_FALLBACKS":                            "2",
+		"SOURCE_FALLBACK_0_KIND":                      "S3",
+		"SOURCE_FALLBACK_0_BUCKET_NAME":               "images",
+		"SOURCE_FALLBACK_0_BUCKET_REGION":             "eu-west-1",
+		"SOURCE_FALLBACK_0_BUCKET_PATHSTYLE":          "false",
+		"SOURCE_FALLBACK_0_BUCKET_INSECURESKIPVERIFY": "false",
+		"SOURCE_FALLBACK_1_KIND":                      "WebFolder",
+		"SOURCE_FALLBACK_1_BASEURL":                   "https://legacy.example.com/images",
+	} {
+		assert.Equal(t, v, cfg.Data[k], k)
+	}
+
+	env := credentialsEnv(darkroom)
+	assert.Len(t, env, 2)
+	assert.Equal(t, "SOURCE_FALLBACK_0_BUCKET_ACCESSKEY", env[0].Name)
+	assert.Equal(t, "s3", env[0].ValueFrom.SecretKeyRef.Name)
+	assert.Equal(t, deploymentsv1alpha1.DefaultAccessKeyKey, env[0].ValueFrom.SecretKeyRef.Key)
+	assert.Equal(t, "SOURCE_FALLBACK_0_BUCKET_SECRETKEY", env[1].Name)
+
+	assert.Equal(t, []string{"s3"}, referencedSecretNames(darkroom))
+}
+
+func TestWebFolderEnv(t *testing.T) {
+	darkroom := deploymentsv1alpha1.Darkroom{
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Source: deploymentsv1alpha1.Source{
+				Type: deploymentsv1alpha1.WebFolder,
+				WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+					BaseURL: "https://example.com",
+					Headers: map[string]deploymentsv1alpha1.SecretKeyRef{
+						"X-Api-Key": {Name: "origin", Key: "apiKey"},
+						"X-Tenant":  {Name: "tenant", Key: "id"},
+					},
+					BasicAuthSecretRef: &deploymentsv1alpha1.BasicAuthSecretRef{Name: "origin-auth"},
+				},
+			},
+			FallbackSources: []deploymentsv1alpha1.Source{
+				{
+					Type: deploymentsv1alpha1.WebFolder,
+					WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+						BaseURL:            "https://legacy.example.com",
+						BasicAuthSecretRef: &deploymentsv1alpha1.BasicAuthSecretRef{Name: "legacy", PasswordKey: "token"},
+					},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "SOURCE_HEADER_X_API_KEY", ValueFrom: secretKeyRef("origin", "apiKey")},
+		{Name: "SOURCE_HEADER_X_TENANT", ValueFrom: secretKeyRef("tenant", "id")},
+		{Name: "SOURCE_BASICAUTH_USERNAME", ValueFrom: secretKeyRef("origin-auth", "username")},
+		{Name: "SOURCE_BASICAUTH_PASSWORD", ValueFrom: secretKeyRef("origin-auth", "password")},
+		{Name: "SOURCE_FALLBACK_0_BASICAUTH_USERNAME", ValueFrom: secretKeyRef("legacy", "username")},
+		{Name: "SOURCE_FALLBACK_0_BASICAUTH_PASSWORD", ValueFrom: secretKeyRef("legacy", "token")},
+	}, credentialsEnv(darkroom))
+	assert.Equal(t, []string{"origin", "tenant", "origin-auth", "legacy"}, referencedSecretNames(darkroom))
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	deploymentsv1alpha1 "github.com/gojekfarm/darkroom-operator/pkg/api/v1alpha1"
+)
+
+var (
+	driftCorrections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "darkroom_drift_corrections_total",
+		Help: "Number of times the fields of a resource owned by a Darkroom were changed outside of the operator and reverted",
+	}, []string{"kind"})
+
+	reconcileStepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "darkroom_reconcile_step_duration_seconds",
+		Help: "Duration of the steps reconciling a Darkroom",
+	}, []string{"namespace", "step"})
+
+	applyErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "darkroom_apply_errors_total",
+		Help: "Number of failures applying the resources owned by a Darkroom",
+	}, []string{"namespace", "kind"})
+
+	validationRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "darkroom_webhook_rejections_total",
+		Help: "Number of invalid fields the validating webhook rejected Darkrooms for",
+	}, []string{"namespace", "field"})
+
+	darkroomsDesc = prometheus.NewDesc(
+		"darkroom_instances",
+		"Number of Darkrooms by source type and deploy state",
+		[]string{"namespace", "source_type", "deploy_state"}, nil,
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(driftCorrections, reconcileStepDuration, applyErrors, validationRejections)
+}
+
+// fieldSubscriptRegexp matches the map keys and indices of a field path, e.g. [KEY] of spec.extraEnv[KEY]
+var fieldSubscriptRegexp = regexp.MustCompile(`\[[^]]*\]`)
+
+// countRejections counts the invalid fields of causes that the Darkroom namespace was rejected for. The field paths
+// are stripped of their map keys and indices so that user input does not end up in the label values.
+func countRejections(namespace string, causes []metav1.StatusCause) {
+	for _, c := range causes {
+		validationRejections.WithLabelValues(namespace, fieldSubscriptRegexp.ReplaceAllString(c.Field, "")).Inc()
+	}
+}
+
+// observeStep records the time since start as the duration of reconciling step of darkroom
+func observeStep(darkroom *deploymentsv1alpha1.Darkroom, step string, start time.Time) {
+	reconcileStepDuration.WithLabelValues(darkroom.Namespace, step).Observe(time.Since(start).Seconds())
+}
+
+// fleetCollector counts the Darkrooms read through reader on every scrape, so that deleted ones never linger
+type fleetCollector struct {
+	reader client.Reader
+}
+
+// registerFleetCollector registers a fleetCollector reading through reader, once per process
+func registerFleetCollector(reader client.Reader) error {
+	err := metrics.Registry.Register(&fleetCollector{reader: reader})
+	if _, ok := err.(prometheus.AlreadyRegisteredError); ok {
+		return nil
+	}
+	return err
+}
+
+func (c *fleetCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- darkroomsDesc
+}
+
+func (c *fleetCollector) Collect(ch chan<- prometheus.Metric) {
+	var list deploymentsv1alpha1.DarkroomList
+	if err := c.reader.List(context.Background(), &list); err != nil {
+		ch <- prometheus.NewInvalidMetric(darkroomsDesc, err)
+		return
+	}
+	counts := make(map[[3]string]int)
+	for _, d := range list.Items {
+		counts[[3]string{d.Namespace, string(d.Spec.Source.Type), string(d.Status.DeployState)}]++
+	}
+	for labels, n := range counts {
+		ch <- prometheus.MustNewConstMetric(darkroomsDesc, prometheus.GaugeValue, float64(n), labels[:]...)
+	}
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/gojekfarm/darkroom-operator/internal/runtime"
+	deploymentsv1alpha1 "github.com/gojekfarm/darkroom-operator/pkg/api/v1alpha1"
+)
+
+func TestFleetCollector(t *testing.T) {
+	darkroom := func(namespace, name string, source deploymentsv1alpha1.Type, state deploymentsv1alpha1.DeployState) *deploymentsv1alpha1.Darkroom {
+		return &deploymentsv1alpha1.Darkroom{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       deploymentsv1alpha1.DarkroomSpec{Source: deploymentsv1alpha1.Source{Type: source}},
+			Status:     deploymentsv1alpha1.DarkroomStatus{DeployState: state},
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(runtime.Scheme()).WithObjects(
+		darkroom("default", "a", deploymentsv1alpha1.WebFolder, deploymentsv1alpha1.Deployed),
+		darkroom("default", "b", deploymentsv1alpha1.WebFolder, deploymentsv1alpha1.Deployed),
+		darkroom("default", "c", deploymentsv1alpha1.S3, deploymentsv1alpha1.Failed),
+		darkroom("images", "a", deploymentsv1alpha1.WebFolder, deploymentsv1alpha1.Deploying),
+	).Build()
+
+	assert.NoError(t, testutil.CollectAndCompare(&fleetCollector{reader: c}, strings.NewReader(`
+# HELP darkroom_instances Number of Darkrooms by source type and deploy state
+# TYPE darkroom_instances gauge
+darkroom_instances{deploy_state="Deployed",namespace="default",source_type="WebFolder"} 2
+darkroom_instances{deploy_state="Deploying",namespace="images",source_type="WebFolder"} 1
+darkroom_instances{deploy_state="Failed",namespace="default",source_type="S3"} 1
+`)))
+}
+
+func TestCountRejections(t *testing.T) {
+	countRejections("rejections", []metav1.StatusCause{
+		{Field: "spec.extraEnv[SECRET_KEY]"},
+		{Field: "spec.extraEnv[OTHER_KEY]"},
+		{Field: "spec.fallbackSources[0].bucket.name"},
+		{Field: "spec.source.baseUrl"},
+	})
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(validationRejections.WithLabelValues("rejections", "spec.extraEnv")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(validationRejections.WithLabelValues("rejections", "spec.fallbackSources.bucket.name")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(validationRejections.WithLabelValues("rejections", "spec.source.baseUrl")))
+}
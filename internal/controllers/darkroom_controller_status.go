@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	deploymentsv1alpha1 "github.com/gojekfarm/darkroom-operator/pkg/api/v1alpha1"
+)
+
+const (
+	reasonRolloutComplete          = "RolloutComplete"
+	reasonRollingOut               = "RollingOut"
+	reasonReplicasAvailable        = "MinimumReplicasAvailable"
+	reasonReplicasUnavailable      = "ReplicasUnavailable"
+	reasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+	reasonReplicaFailure           = "ReplicaFailure"
+	reasonAsExpected               = "AsExpected"
+	reasonSourceConfigured         = "SourceConfigured"
+	reasonSourceUnresolved         = "SourceUnresolved"
+	reasonSourceMisconfigured      = "SourceMisconfigured"
+	reasonDeploymentHeld           = "DeploymentHeld"
+	reasonCreated                  = "Created"
+	reasonUpdated                  = "Updated"
+	reasonDriftCorrected           = "DriftCorrected"
+	reasonPaused                   = "Paused"
+	reasonNotPaused                = "NotPaused"
+	reasonResumed                  = "Resumed"
+	reasonReconcileRequested       = "ReconcileRequested"
+	reasonReconcileSucceeded       = "ReconcileSucceeded"
+	reasonReconcileFailed          = "ReconcileFailed"
+	reasonGetDeploymentFailed      = "GetDeploymentFailed"
+	reasonPatchStatusFailed        = "PatchStatusFailed"
+	reasonGetRouteFailed           = "GetRouteFailed"
+	reasonDeleteIngressFailed      = "DeleteIngressFailed"
+	reasonDeleteHTTPRouteFailed    = "DeleteHTTPRouteFailed"
+	reasonGetCertificateFailed     = "GetCertificateFailed"
+	reasonDeleteCertificateFailed  = "DeleteCertificateFailed"
+	reasonCertificateIssued        = "CertificateIssued"
+	reasonCertificatePending       = "CertificatePending"
+	reasonCertManagerNotInstalled  = "CertManagerNotInstalled"
+	reasonGatewayAPIUnavailable    = "GatewayAPIUnavailable"
+
+	reasonDeleteHorizontalPodAutoscalerFailed = "DeleteHorizontalPodAutoscalerFailed"
+	reasonDeletePodDisruptionBudgetFailed     = "DeletePodDisruptionBudgetFailed"
+	reasonDeleteNetworkPolicyFailed           = "DeleteNetworkPolicyFailed"
+	reasonDeleteServiceMonitorFailed          = "DeleteServiceMonitorFailed"
+
+	reasonAddFinalizerFailed    = "AddFinalizerFailed"
+	reasonRemoveFinalizerFailed = "RemoveFinalizerFailed"
+	reasonDeleteRouteFailed     = "DeleteRouteFailed"
+	reasonDeleteWorkloadFailed  = "DeleteWorkloadFailed"
+	reasonOrphanFailed          = "OrphanFailed"
+
+	reasonValidationFailed = "ValidationFailed"
+	reasonDeleteForbidden  = "DeleteForbidden"
+)
+
+// reconcileError is the failure of a single reconcile step, with the reason it was reported under
+type reconcileError struct {
+	reason string
+	err    error
+}
+
+func (e *reconcileError) Error() string {
+	return e.err.Error()
+}
+
+func (e *reconcileError) Unwrap() error {
+	return e.err
+}
+
+// setDeploymentStatus derives the replica counts, conditions and DeployState of darkroom
+// from the status of the Deployment it owns
+func setDeploymentStatus(darkroom *deploymentsv1alpha1.Darkroom, depl *appsv1.Deployment) {
+	status := &darkroom.Status
+	status.ObservedGeneration = darkroom.Generation
+	status.Replicas = depl.Status.Replicas
+	status.UpdatedReplicas = depl.Status.UpdatedReplicas
+	status.ReadyReplicas = depl.Status.ReadyReplicas
+	status.AvailableReplicas = depl.Status.AvailableReplicas
+	status.ConfigHash = depl.Spec.Template.Annotations[configHashAnnotation]
+	if depl.Spec.Selector != nil {
+		status.Selector = metav1.FormatLabelSelector(depl.Spec.Selector)
+	}
+
+	desired := int32(1)
+	if depl.Spec.Replicas != nil {
+		desired = *depl.Spec.Replicas
+	}
+
+	degraded := metav1.Condition{
+		Type:    deploymentsv1alpha1.DegradedCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonAsExpected,
+		Message: "Deployment is rolling out as expected",
+	}
+	if c := deploymentCondition(depl, appsv1.DeploymentProgressing); c != nil && c.Reason == reasonProgressDeadlineExceeded {
+		degraded.Status, degraded.Reason, degraded.Message = metav1.ConditionTrue, reasonProgressDeadlineExceeded, c.Message
+	} else if c := deploymentCondition(depl, appsv1.DeploymentReplicaFailure); c != nil && c.Status == corev1.ConditionTrue {
+		degraded.Status, degraded.Reason, degraded.Message = metav1.ConditionTrue, reasonReplicaFailure, c.Message
+	}
+
+	progressing := metav1.Condition{
+		Type:    deploymentsv1alpha1.ProgressingCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  reasonRollingOut,
+		Message: fmt.Sprintf("%d of %d updated replicas are available", depl.Status.AvailableReplicas, desired),
+	}
+	rolledOut := depl.Status.ObservedGeneration >= depl.Generation &&
+		depl.Status.UpdatedReplicas >= desired &&
+		depl.Status.Replicas == depl.Status.UpdatedReplicas &&
+		depl.Status.AvailableReplicas >= desired
+	if rolledOut || degraded.Status == metav1.ConditionTrue {
+		progressing.Status, progressing.Reason = metav1.ConditionFalse, reasonRolloutComplete
+		if degraded.Status == metav1.ConditionTrue {
+			progressing.Reason = degraded.Reason
+		}
+	}
+
+	ready := metav1.Condition{
+		Type:    deploymentsv1alpha1.ReadyCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonReplicasUnavailable,
+		Message: fmt.Sprintf("%d of %d replicas are available", depl.Status.AvailableReplicas, desired),
+	}
+	if depl.Status.AvailableReplicas >= desired {
+		ready.Status, ready.Reason = metav1.ConditionTrue, reasonReplicasAvailable
+	}
+
+	for _, c := range []metav1.Condition{ready, progressing, degraded} {
+		setCondition(darkroom, c)
+	}
+
+	switch {
+	case !darkroom.DeletionTimestamp.IsZero():
+		status.DeployState = deploymentsv1alpha1.Deleting
+	case degraded.Status == metav1.ConditionTrue:
+		status.DeployState = deploymentsv1alpha1.Failed
+	case ready.Status == metav1.ConditionTrue && rolledOut:
+		status.DeployState = deploymentsv1alpha1.Deployed
+	default:
+		status.DeployState = deploymentsv1alpha1.Deploying
+	}
+}
+
+// setSourceCondition reports whether the source of darkroom, including its credentials, could be resolved
+func setSourceCondition(darkroom *deploymentsv1alpha1.Darkroom, err error) {
+	c := metav1.Condition{
+		Type:    deploymentsv1alpha1.SourceReachableCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  reasonSourceConfigured,
+		Message: fmt.Sprintf("%s source is configured", darkroom.Spec.Source.Type),
+	}
+	if err != nil {
+		c.Status, c.Reason, c.Message = metav1.ConditionFalse, reasonSourceUnresolved, err.Error()
+	}
+	setCondition(darkroom, c)
+}
+
+// setSourcesStatus reports whether the source and each fallback source of darkroom validated successfully,
+// credsErr and fallbackErrs are the errors resolving their credentials
+func setSourcesStatus(darkroom *deploymentsv1alpha1.Darkroom, credsErr error, fallbackErrs []error) {
+	sourceStatus := func(path *field.Path, s deploymentsv1alpha1.Source, err error) deploymentsv1alpha1.SourceStatus {
+		status := deploymentsv1alpha1.SourceStatus{Path: path.String(), Type: s.Type, Valid: true}
+		if errs := s.Validate(path); len(errs) > 0 {
+			status.Valid, status.Message = false, errs.ToAggregate().Error()
+		} else if err != nil {
+			status.Valid, status.Message = false, err.Error()
+		}
+		return status
+	}
+	sources := []deploymentsv1alpha1.SourceStatus{
+		sourceStatus(field.NewPath("spec").Child("source"), darkroom.Spec.Source, credsErr),
+	}
+	for i, s := range darkroom.Spec.FallbackSources {
+		var err error
+		if i < len(fallbackErrs) {
+			err = fallbackErrs[i]
+		}
+		sources = append(sources, sourceStatus(field.NewPath("spec").Child("fallbackSources").Index(i), s, err))
+	}
+	darkroom.Status.Sources = sources
+}
+
+// setReconciledCondition reports the first failing reconcile step of errs, if any
+func setReconciledCondition(darkroom *deploymentsv1alpha1.Darkroom, errs []error) {
+	c := metav1.Condition{
+		Type:    deploymentsv1alpha1.ReconciledCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  reasonReconcileSucceeded,
+		Message: "All resources are applied",
+	}
+	if len(errs) > 0 {
+		c.Status, c.Reason, c.Message = metav1.ConditionFalse, reasonReconcileFailed, utilerrors.NewAggregate(errs).Error()
+		var re *reconcileError
+		if errors.As(errs[0], &re) {
+			c.Reason = re.reason
+		}
+	}
+	setCondition(darkroom, c)
+}
+
+// setCertificateCondition reports whether the Certificate requested through spec.tls has been issued.
+// cert is nil when it could not be read, or when cert-manager is not installed.
+func setCertificateCondition(darkroom *deploymentsv1alpha1.Darkroom, cert *unstructured.Unstructured, certManager bool) {
+	if darkroom.Spec.TLS == nil {
+		meta.RemoveStatusCondition(&darkroom.Status.Conditions, deploymentsv1alpha1.CertificateReadyCondition)
+		return
+	}
+	c := metav1.Condition{
+		Type:    deploymentsv1alpha1.CertificateReadyCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonCertificatePending,
+		Message: "Certificate has not been issued yet",
+	}
+	switch {
+	case !certManager:
+		c.Reason, c.Message = reasonCertManagerNotInstalled,
+			"cert-manager CRDs are not installed in the cluster, the Domains are served without the spec.tls Secret"
+	case cert != nil:
+		conditions, _, _ := unstructured.NestedSlice(cert.Object, "status", "conditions")
+		for _, i := range conditions {
+			cond, ok := i.(map[string]interface{})
+			if !ok || cond["type"] != "Ready" {
+				continue
+			}
+			if msg, ok := cond["message"].(string); ok && msg != "" {
+				c.Message = msg
+			}
+			if cond["status"] == string(metav1.ConditionTrue) {
+				c.Status, c.Reason = metav1.ConditionTrue, reasonCertificateIssued
+			}
+		}
+	}
+	setCondition(darkroom, c)
+}
+
+// admittedIngressHosts returns the hosts of ing once the ingress controller has published an address for ing
+func admittedIngressHosts(ing *networkingv1.Ingress) []string {
+	if len(ing.Status.LoadBalancer.Ingress) == 0 {
+		return nil
+	}
+	var hosts []string
+	for _, rule := range ing.Spec.Rules {
+		hosts = append(hosts, rule.Host)
+	}
+	return hosts
+}
+
+// admittedRouteHosts returns the hostnames of route once a parent Gateway has accepted route
+func admittedRouteHosts(route *unstructured.Unstructured) []string {
+	parents, _, _ := unstructured.NestedSlice(route.Object, "status", "parents")
+	for _, p := range parents {
+		parent, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		conditions, _, _ := unstructured.NestedSlice(parent, "conditions")
+		for _, c := range conditions {
+			cond, ok := c.(map[string]interface{})
+			if ok && cond["type"] == "Accepted" && cond["status"] == string(metav1.ConditionTrue) {
+				hosts, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+				return hosts
+			}
+		}
+	}
+	return nil
+}
+
+// setPausedCondition reports whether the reconciliation of darkroom is paused
+func setPausedCondition(darkroom *deploymentsv1alpha1.Darkroom, paused bool) {
+	c := metav1.Condition{
+		Type:    deploymentsv1alpha1.PausedCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonNotPaused,
+		Message: "Resources are applied by the controller",
+	}
+	if paused {
+		c.Status, c.Reason = metav1.ConditionTrue, reasonPaused
+		c.Message = fmt.Sprintf("Resources are left as they are while the %s annotation is set", deploymentsv1alpha1.PausedAnnotation)
+	}
+	setCondition(darkroom, c)
+}
+
+func setCondition(darkroom *deploymentsv1alpha1.Darkroom, c metav1.Condition) {
+	c.ObservedGeneration = darkroom.Generation
+	meta.SetStatusCondition(&darkroom.Status.Conditions, c)
+}
+
+func deploymentCondition(depl *appsv1.Deployment, t appsv1.DeploymentConditionType) *appsv1.DeploymentCondition {
+	for i := range depl.Status.Conditions {
+		if depl.Status.Conditions[i].Type == t {
+			return &depl.Status.Conditions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	deploymentsv1alpha1 "github.com/gojekfarm/darkroom-operator/pkg/api/v1alpha1"
+)
+
+func TestSetDeploymentStatus(t *testing.T) {
+	replicas := int32(2)
+	now := metav1.Now()
+	testcases := []struct {
+		name        string
+		darkroom    deploymentsv1alpha1.Darkroom
+		depl        appsv1.Deployment
+		wantState   deploymentsv1alpha1.DeployState
+		wantReady   metav1.ConditionStatus
+		wantRolling metav1.ConditionStatus
+		wantFailed  metav1.ConditionStatus
+	}{
+		{
+			name:        "NewDeployment",
+			depl:        appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: &replicas}},
+			wantState:   deploymentsv1alpha1.Deploying,
+			wantReady:   metav1.ConditionFalse,
+			wantRolling: metav1.ConditionTrue,
+			wantFailed:  metav1.ConditionFalse,
+		},
+		{
+			name: "RolledOut",
+			depl: appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: &replicas,
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{configHashAnnotation: "abc"}},
+					},
+				},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 2,
+					Replicas:           2,
+					UpdatedReplicas:    2,
+					ReadyReplicas:      2,
+					AvailableReplicas:  2,
+				},
+			},
+			wantState:   deploymentsv1alpha1.Deployed,
+			wantReady:   metav1.ConditionTrue,
+			wantRolling: metav1.ConditionFalse,
+			wantFailed:  metav1.ConditionFalse,
+		},
+		{
+			name: "RollingOutNewGeneration",
+			depl: appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 3,
+					Replicas:           3,
+					UpdatedReplicas:    1,
+					ReadyReplicas:      2,
+					AvailableReplicas:  2,
+				},
+			},
+			wantState:   deploymentsv1alpha1.Deploying,
+			wantReady:   metav1.ConditionTrue,
+			wantRolling: metav1.ConditionTrue,
+			wantFailed:  metav1.ConditionFalse,
+		},
+		{
+			name: "ProgressDeadlineExceeded",
+			depl: appsv1.Deployment{
+				Spec: appsv1.DeploymentSpec{Replicas: &replicas},
+				Status: appsv1.DeploymentStatus{
+					Replicas:        2,
+					UpdatedReplicas: 2,
+					Conditions: []appsv1.DeploymentCondition{
+						{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+					},
+				},
+			},
+			wantState:   deploymentsv1alpha1.Failed,
+			wantReady:   metav1.ConditionFalse,
+			wantRolling: metav1.ConditionFalse,
+			wantFailed:  metav1.ConditionTrue,
+		},
+		{
+			name: "ReplicaFailure",
+			depl: appsv1.Deployment{
+				Spec: appsv1.DeploymentSpec{Replicas: &replicas},
+				Status: appsv1.DeploymentStatus{
+					Conditions: []appsv1.DeploymentCondition{
+						{Type: appsv1.DeploymentReplicaFailure, Status: corev1.ConditionTrue, Reason: "FailedCreate"},
+					},
+				},
+			},
+			wantState:   deploymentsv1alpha1.Failed,
+			wantReady:   metav1.ConditionFalse,
+			wantRolling: metav1.ConditionFalse,
+			wantFailed:  metav1.ConditionTrue,
+		},
+		{
+			name:        "Deleting",
+			darkroom:    deploymentsv1alpha1.Darkroom{ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now}},
+			depl:        appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: &replicas}},
+			wantState:   deploymentsv1alpha1.Deleting,
+			wantReady:   metav1.ConditionFalse,
+			wantRolling: metav1.ConditionTrue,
+			wantFailed:  metav1.ConditionFalse,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			setDeploymentStatus(&tc.darkroom, &tc.depl)
+			conditions := tc.darkroom.Status.Conditions
+			assert.Equal(t, tc.wantState, tc.darkroom.Status.DeployState)
+			assert.Equal(t, tc.depl.Status.AvailableReplicas, tc.darkroom.Status.AvailableReplicas)
+			assert.Equal(t, tc.depl.Spec.Template.Annotations[configHashAnnotation], tc.darkroom.Status.ConfigHash)
+			assert.Equal(t, tc.wantReady, meta.FindStatusCondition(conditions, deploymentsv1alpha1.ReadyCondition).Status)
+			assert.Equal(t, tc.wantRolling, meta.FindStatusCondition(conditions, deploymentsv1alpha1.ProgressingCondition).Status)
+			assert.Equal(t, tc.wantFailed, meta.FindStatusCondition(conditions, deploymentsv1alpha1.DegradedCondition).Status)
+		})
+	}
+}
+
+func TestSetSourceCondition(t *testing.T) {
+	d := deploymentsv1alpha1.Darkroom{ObjectMeta: metav1.ObjectMeta{Generation: 4}}
+
+	setSourceCondition(&d, nil)
+	c := meta.FindStatusCondition(d.Status.Conditions, deploymentsv1alpha1.SourceReachableCondition)
+	assert.Equal(t, metav1.ConditionTrue, c.Status)
+	assert.Equal(t, int64(4), c.ObservedGeneration)
+
+	setSourceCondition(&d, errors.New(`secrets "creds" not found`))
+	c = meta.FindStatusCondition(d.Status.Conditions, deploymentsv1alpha1.SourceReachableCondition)
+	assert.Equal(t, metav1.ConditionFalse, c.Status)
+	assert.Equal(t, `secrets "creds" not found`, c.Message)
+}
+
+func TestSetSourcesStatus(t *testing.T) {
+	d := deploymentsv1alpha1.Darkroom{
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Source: deploymentsv1alpha1.Source{
+				Type:          deploymentsv1alpha1.WebFolder,
+				WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{BaseURL: "https://example.com"},
+			},
+			FallbackSources: []deploymentsv1alpha1.Source{
+				{
+					Type: deploymentsv1alpha1.S3,
+					Bucket: &deploymentsv1alpha1.Bucket{
+						Name:                 "images",
+						CredentialsSecretRef: &deploymentsv1alpha1.CredentialsSecretRef{Name: "creds"},
+					},
+				},
+				{Type: deploymentsv1alpha1.WebFolder},
+			},
+		},
+	}
+
+	setSourcesStatus(&d, nil, []error{errors.New(`secrets "creds" not found`), nil})
+	assert.Equal(t, []deploymentsv1alpha1.SourceStatus{
+		{Path: "spec.source", Type: deploymentsv1alpha1.WebFolder, Valid: true},
+		{Path: "spec.fallbackSources[0]", Type: deploymentsv1alpha1.S3, Message: `secrets "creds" not found`},
+		{
+			Path:    "spec.fallbackSources[1]",
+			Type:    deploymentsv1alpha1.WebFolder,
+			Message: `spec.fallbackSources[1].baseUrl: Invalid value: "": parse "": empty url`,
+		},
+	}, d.Status.Sources)
+}
+
+func TestRecordTransitions(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &DarkroomReconciler{Recorder: recorder}
+	old := &deploymentsv1alpha1.Darkroom{
+		Status: deploymentsv1alpha1.DarkroomStatus{
+			DeployState: deploymentsv1alpha1.Deploying,
+			Sources: []deploymentsv1alpha1.SourceStatus{
+				{Path: "spec.source", Valid: true},
+				{Path: "spec.fallbackSources[0]", Message: "bucket is required"},
+			},
+		},
+	}
+	d := old.DeepCopy()
+	d.Spec.Version = "v1"
+	d.Status.DeployState = deploymentsv1alpha1.Deployed
+	d.Status.AvailableReplicas = 2
+	d.Status.Sources = []deploymentsv1alpha1.SourceStatus{
+		{Path: "spec.source", Message: `secrets "creds" not found`},
+		{Path: "spec.fallbackSources[0]", Message: "bucket is required"},
+		{Path: "spec.fallbackSources[1]", Message: "baseUrl is required"},
+	}
+
+	r.recordTransitions(old, d)
+	close(recorder.Events)
+	var events []string
+	for e := range recorder.Events {
+		events = append(events, e)
+	}
+	assert.Equal(t, []string{
+		"Normal RolloutComplete Rolled out version v1 to 2 replicas",
+		`Warning SourceMisconfigured spec.source: secrets "creds" not found`,
+		"Warning SourceMisconfigured spec.fallbackSources[1]: baseUrl is required",
+	}, events)
+
+	recorder = record.NewFakeRecorder(10)
+	r.Recorder = recorder
+	r.recordTransitions(d, d.DeepCopy())
+	assert.Empty(t, recorder.Events)
+
+	paused := d.DeepCopy()
+	setPausedCondition(paused, true)
+	resumed := d.DeepCopy()
+	resumed.Status.LastHandledReconcileAt = "2021-08-01T10:00:00Z"
+	setPausedCondition(resumed, false)
+	r.recordTransitions(paused, resumed)
+	assert.Equal(t, "Normal Resumed Reconciliation is resumed", <-recorder.Events)
+	assert.Equal(t, "Normal ReconcileRequested Reconciled as requested at 2021-08-01T10:00:00Z", <-recorder.Events)
+	assert.Empty(t, recorder.Events)
+}
+
+func TestSetPausedCondition(t *testing.T) {
+	d := deploymentsv1alpha1.Darkroom{}
+
+	setPausedCondition(&d, true)
+	c := meta.FindStatusCondition(d.Status.Conditions, deploymentsv1alpha1.PausedCondition)
+	assert.Equal(t, metav1.ConditionTrue, c.Status)
+	assert.Equal(t, "Paused", c.Reason)
+
+	setPausedCondition(&d, false)
+	c = meta.FindStatusCondition(d.Status.Conditions, deploymentsv1alpha1.PausedCondition)
+	assert.Equal(t, metav1.ConditionFalse, c.Status)
+	assert.Equal(t, "NotPaused", c.Reason)
+}
+
+func TestSetReconciledCondition(t *testing.T) {
+	d := deploymentsv1alpha1.Darkroom{}
+
+	setReconciledCondition(&d, nil)
+	c := meta.FindStatusCondition(d.Status.Conditions, deploymentsv1alpha1.ReconciledCondition)
+	assert.Equal(t, metav1.ConditionTrue, c.Status)
+
+	setReconciledCondition(&d, []error{
+		&reconcileError{reason: "ApplyServiceFailed", err: errors.New("unable to apply Service: forbidden")},
+		errors.New("unable to patch status"),
+	})
+	c = meta.FindStatusCondition(d.Status.Conditions, deploymentsv1alpha1.ReconciledCondition)
+	assert.Equal(t, metav1.ConditionFalse, c.Status)
+	assert.Equal(t, "ApplyServiceFailed", c.Reason)
+	assert.Equal(t, "[unable to apply Service: forbidden, unable to patch status]", c.Message)
+}
+
+func TestAdmittedIngressHosts(t *testing.T) {
+	ing := networkingv1.Ingress{
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{Host: "a.darkroom.net"}, {Host: "b.darkroom.net"}},
+		},
+	}
+	assert.Empty(t, admittedIngressHosts(&ing))
+
+	ing.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: "lb.example.com"}}
+	assert.Equal(t, []string{"a.darkroom.net", "b.darkroom.net"}, admittedIngressHosts(&ing))
+}
+
+func TestAdmittedRouteHosts(t *testing.T) {
+	route := newHTTPRoute()
+	route.Object["spec"] = map[string]interface{}{
+		"hostnames": []interface{}{"a.darkroom.net"},
+	}
+	assert.Empty(t, admittedRouteHosts(route))
+
+	parent := func(status string) interface{} {
+		return map[string]interface{}{
+			"parentRef":  map[string]interface{}{"name": "gateway"},
+			"conditions": []interface{}{map[string]interface{}{"type": "Accepted", "status": status}},
+		}
+	}
+	route.Object["status"] = map[string]interface{}{"parents": []interface{}{parent("False")}}
+	assert.Empty(t, admittedRouteHosts(route))
+
+	route.Object["status"] = map[string]interface{}{"parents": []interface{}{parent("False"), parent("True")}}
+	assert.Equal(t, []string{"a.darkroom.net"}, admittedRouteHosts(route))
+}
+
+func TestSetCertificateCondition(t *testing.T) {
+	d := deploymentsv1alpha1.Darkroom{
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			TLS: &deploymentsv1alpha1.TLS{IssuerRef: deploymentsv1alpha1.IssuerRef{Name: "letsencrypt"}},
+		},
+	}
+	cert := newCertificate()
+
+	setCertificateCondition(&d, nil, false)
+	c := meta.FindStatusCondition(d.Status.Conditions, deploymentsv1alpha1.CertificateReadyCondition)
+	assert.Equal(t, metav1.ConditionFalse, c.Status)
+	assert.Equal(t, "CertManagerNotInstalled", c.Reason)
+
+	setCertificateCondition(&d, cert, true)
+	c = meta.FindStatusCondition(d.Status.Conditions, deploymentsv1alpha1.CertificateReadyCondition)
+	assert.Equal(t, metav1.ConditionFalse, c.Status)
+	assert.Equal(t, "CertificatePending", c.Reason)
+
+	cert.Object["status"] = map[string]interface{}{
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "True", "message": "Certificate is up to date and has not expired"},
+		},
+	}
+	setCertificateCondition(&d, cert, true)
+	c = meta.FindStatusCondition(d.Status.Conditions, deploymentsv1alpha1.CertificateReadyCondition)
+	assert.Equal(t, metav1.ConditionTrue, c.Status)
+	assert.Equal(t, "CertificateIssued", c.Reason)
+	assert.Equal(t, "Certificate is up to date and has not expired", c.Message)
+
+	d.Spec.TLS = nil
+	setCertificateCondition(&d, nil, true)
+	assert.Nil(t, meta.FindStatusCondition(d.Status.Conditions, deploymentsv1alpha1.CertificateReadyCondition))
+}
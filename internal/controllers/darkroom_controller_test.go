@@ -26,18 +26,27 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/suite"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/gojekfarm/darkroom-operator/internal/runtime"
 	"github.com/gojekfarm/darkroom-operator/internal/testhelper"
@@ -50,6 +59,7 @@ type DarkroomControllerSuite struct {
 	testEnv    testhelper.Environment
 	client     client.Client
 	reconciler *DarkroomReconciler
+	recorder   *record.FakeRecorder
 }
 
 func TestDarkroomControllerSuite(t *testing.T) {
@@ -58,9 +68,11 @@ func TestDarkroomControllerSuite(t *testing.T) {
 
 func (s *DarkroomControllerSuite) SetupSuite() {
 	s.testEnv = testhelper.NewTestEnvironment("..", "..")
+	s.recorder = record.NewFakeRecorder(100)
 	s.reconciler = &DarkroomReconciler{
-		Log:    s.testEnv.GetLogger().WithName("controllers").WithName("Darkroom"),
-		Scheme: runtime.Scheme(),
+		Log:      s.testEnv.GetLogger().WithName("controllers").WithName("Darkroom"),
+		Scheme:   runtime.Scheme(),
+		Recorder: s.recorder,
 	}
 	s.testEnv.Add(s.reconciler)
 	s.NoError(s.testEnv.Start())
@@ -73,9 +85,14 @@ func (s *DarkroomControllerSuite) SetupSuite() {
 
 func (s *DarkroomControllerSuite) SetupTest() {
 	s.testEnv.ResetLogs()
+	for len(s.recorder.Events) > 0 {
+		<-s.recorder.Events
+	}
 }
 
 func (s *DarkroomControllerSuite) TestReconcile() {
+	replicas := int32(3)
+	ingressClass := "nginx"
 	testcases := []struct {
 		name             string
 		ctx              context.Context
@@ -102,7 +119,16 @@ func (s *DarkroomControllerSuite) TestReconcile() {
 				},
 			},
 			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
-				return c.Create(ctx, d)
+				if err := c.Create(ctx, d); err != nil {
+					return err
+				}
+				// there is no ingress controller in the test environment to admit the hosts
+				ing := &networkingv1.Ingress{}
+				s.Eventually(func() bool {
+					return c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, ing) == nil
+				}, 10*time.Second, 250*time.Millisecond)
+				ing.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "10.0.0.1"}}
+				return c.Status().Update(ctx, ing)
 			},
 			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
 				desired := &deploymentsv1alpha1.Darkroom{}
@@ -226,6 +252,9 @@ func (s *DarkroomControllerSuite) TestReconcile() {
 				s.Equal("latest", strings.Split(depl.Spec.Template.Spec.Containers[0].Image, ":")[1])
 				s.Equal(d.Name, depl.Spec.Template.Spec.Containers[0].EnvFrom[0].ConfigMapRef.Name)
 				s.Equal(deploymentsv1alpha1.Deploying, desired.Status.DeployState)
+				s.Equal(desired.Generation, desired.Status.ObservedGeneration)
+				s.Equal(metav1.ConditionFalse, meta.FindStatusCondition(desired.Status.Conditions, deploymentsv1alpha1.ReadyCondition).Status)
+				s.Equal(metav1.ConditionTrue, meta.FindStatusCondition(desired.Status.Conditions, deploymentsv1alpha1.SourceReachableCondition).Status)
 				s.True(len(depl.OwnerReferences) > 0)
 				s.Equal(deploymentsv1alpha1.GroupVersion.String(), depl.OwnerReferences[0].APIVersion)
 				return nil
@@ -279,6 +308,871 @@ func (s *DarkroomControllerSuite) TestReconcile() {
 				return nil
 			},
 		},
+		{
+			name: "Reconciler wires the referenced credentials Secret into the Deployment",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-secret-ref",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.S3,
+						Bucket: &deploymentsv1alpha1.Bucket{
+							Name:                 "darkroom-bucket",
+							CredentialsSecretRef: &deploymentsv1alpha1.CredentialsSecretRef{Name: "darkroom-s3-credentials"},
+						},
+					},
+					Domains: []string{"secret-ref.darkroom.net"},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				secret := &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "darkroom-s3-credentials", Namespace: d.Namespace},
+					StringData: map[string]string{"accessKey": "some-key", "secretKey": "super-secret"},
+				}
+				if err := c.Create(ctx, secret); err != nil {
+					return err
+				}
+				return c.Create(ctx, d)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				depl := &appsv1.Deployment{}
+				if err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, depl); err != nil {
+					return err
+				}
+				secret := &corev1.Secret{}
+				if err := c.Get(ctx, client.ObjectKey{Name: "darkroom-s3-credentials", Namespace: d.Namespace}, secret); err != nil {
+					return err
+				}
+				s.Equal([]corev1.EnvVar{
+					{Name: "SOURCE_BUCKET_ACCESSKEY", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "darkroom-s3-credentials"},
+						Key:                  "accessKey",
+					}}},
+					{Name: "SOURCE_BUCKET_SECRETKEY", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "darkroom-s3-credentials"},
+						Key:                  "secretKey",
+					}}},
+				}, depl.Spec.Template.Spec.Containers[0].Env)
+				cfg := &corev1.ConfigMap{}
+				if err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, cfg); err != nil {
+					return err
+				}
+				s.Equal(configHash(*cfg, secret), depl.Spec.Template.Annotations[configHashAnnotation])
+				return nil
+			},
+		},
+		{
+			name: "Reconciler moves inline credentials into an owned Secret",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-inline-credentials",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.GoogleCloudStorage,
+						Bucket: &deploymentsv1alpha1.Bucket{
+							Name:            "darkroom-bucket",
+							CredentialsJson: `{"type":"service_account"}`,
+						},
+					},
+					Domains: []string{"inline-credentials.darkroom.net"},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				return c.Create(ctx, d)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				secret := &corev1.Secret{}
+				if err := c.Get(ctx, client.ObjectKey{Name: d.Name + "-credentials", Namespace: d.Namespace}, secret); err != nil {
+					return err
+				}
+				depl := &appsv1.Deployment{}
+				if err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, depl); err != nil {
+					return err
+				}
+				s.Equal(`{"type":"service_account"}`, string(secret.Data["credentialsJson"]))
+				s.True(len(secret.OwnerReferences) > 0)
+				s.Equal([]corev1.EnvVar{
+					{Name: "SOURCE_BUCKET_CREDENTIALSJSON", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: d.Name + "-credentials"},
+						Key:                  "credentialsJson",
+					}}},
+				}, depl.Spec.Template.Spec.Containers[0].Env)
+				return nil
+			},
+		},
+		{
+			name: "Reconciler surfaces a rejected apply",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-rejected",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.WebFolder,
+						WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+							BaseURL: "https://example.com/assets/images",
+						},
+					},
+					Domains: []string{"rejected.darkroom.net"},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				// an immutable ConfigMap makes the api-server reject the apply of the desired ConfigMap
+				immutable := true
+				cfg := &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: d.Name, Namespace: d.Namespace},
+					Data:       map[string]string{"SOURCE_KIND": "S3"},
+					Immutable:  &immutable,
+				}
+				if err := c.Create(ctx, cfg); err != nil {
+					return err
+				}
+				return c.Create(ctx, d)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				_, err := s.reconciler.Reconcile(ctx, ctrl.Request{
+					NamespacedName: types.NamespacedName{Namespace: d.Namespace, Name: d.Name},
+				})
+				s.Error(err)
+				s.Contains(err.Error(), "unable to apply ConfigMap")
+
+				desired := &deploymentsv1alpha1.Darkroom{}
+				if err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, desired); err != nil {
+					return err
+				}
+				cond := meta.FindStatusCondition(desired.Status.Conditions, deploymentsv1alpha1.ReconciledCondition)
+				s.NotNil(cond)
+				s.Equal(metav1.ConditionFalse, cond.Status)
+				s.Equal("ApplyConfigMapFailed", cond.Reason)
+
+				// the remaining steps still ran
+				depl := &appsv1.Deployment{}
+				s.NoError(c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, depl))
+
+				var events []string
+				for len(s.recorder.Events) > 0 {
+					events = append(events, <-s.recorder.Events)
+				}
+				s.Contains(strings.Join(events, "\n"), "Warning ApplyConfigMapFailed unable to apply ConfigMap")
+				s.Contains(strings.Join(events, "\n"), "Normal Created Deployment "+d.Name)
+				s.GreaterOrEqual(testutil.ToFloat64(applyErrors.WithLabelValues(d.Namespace, "ConfigMap")), float64(1))
+				return nil
+			},
+		},
+		{
+			name: "Reconciler routes the domains through an Ingress",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-ingress",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.WebFolder,
+						WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+							BaseURL: "https://example.com/assets/images",
+						},
+					},
+					PathPrefix: "images",
+					Domains:    []string{"ingress.darkroom.net"},
+					Ingress: &deploymentsv1alpha1.Ingress{
+						ClassName:     &ingressClass,
+						Annotations:   map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "0"},
+						TLSSecretName: "darkroom-tls",
+					},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				return c.Create(ctx, d)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				ing := &networkingv1.Ingress{}
+				if err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, ing); err != nil {
+					return err
+				}
+				s.Equal("nginx", *ing.Spec.IngressClassName)
+				s.Equal("0", ing.Annotations["nginx.ingress.kubernetes.io/proxy-body-size"])
+				s.Equal([]networkingv1.IngressTLS{{Hosts: d.Spec.Domains, SecretName: "darkroom-tls"}}, ing.Spec.TLS)
+				s.Len(ing.Spec.Rules, 1)
+				s.Equal("ingress.darkroom.net", ing.Spec.Rules[0].Host)
+				path := ing.Spec.Rules[0].HTTP.Paths[0]
+				s.Equal("/images", path.Path)
+				s.Equal(d.Name, path.Backend.Service.Name)
+				s.Equal("http", path.Backend.Service.Port.Name)
+
+				desired := &deploymentsv1alpha1.Darkroom{}
+				if err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, desired); err != nil {
+					return err
+				}
+				s.Empty(desired.Status.Domains)
+				return nil
+			},
+		},
+		{
+			name: "Reconciler reports a Gateway it can not route through",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-no-gateway-api",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.WebFolder,
+						WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+							BaseURL: "https://example.com/assets/images",
+						},
+					},
+					Domains: []string{"gateway.darkroom.net"},
+					Ingress: &deploymentsv1alpha1.Ingress{
+						GatewayRef: &deploymentsv1alpha1.GatewayRef{Name: "public"},
+					},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				return c.Create(ctx, d)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				_, err := s.reconciler.Reconcile(ctx, ctrl.Request{
+					NamespacedName: types.NamespacedName{Namespace: d.Namespace, Name: d.Name},
+				})
+				s.NoError(err)
+
+				if err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, &networkingv1.Ingress{}); err != nil {
+					return err
+				}
+				var events []string
+				for len(s.recorder.Events) > 0 {
+					events = append(events, <-s.recorder.Events)
+				}
+				s.Contains(strings.Join(events, "\n"), "Warning GatewayAPIUnavailable spec.ingress.gatewayRef is set")
+				return nil
+			},
+		},
+		{
+			name: "Reconciler reports TLS without cert-manager installed",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-no-cert-manager",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.WebFolder,
+						WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+							BaseURL: "https://example.com/assets/images",
+						},
+					},
+					Domains: []string{"tls.darkroom.net"},
+					TLS: &deploymentsv1alpha1.TLS{
+						IssuerRef:  deploymentsv1alpha1.IssuerRef{Name: "letsencrypt"},
+						SecretName: "darkroom-cert",
+					},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				return c.Create(ctx, d)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				desired := &deploymentsv1alpha1.Darkroom{}
+				if err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, desired); err != nil {
+					return err
+				}
+				cond := meta.FindStatusCondition(desired.Status.Conditions, deploymentsv1alpha1.CertificateReadyCondition)
+				s.NotNil(cond)
+				s.Equal(metav1.ConditionFalse, cond.Status)
+				s.Equal("CertManagerNotInstalled", cond.Reason)
+				s.True(meta.IsStatusConditionTrue(desired.Status.Conditions, deploymentsv1alpha1.ReconciledCondition))
+
+				ing := &networkingv1.Ingress{}
+				if err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, ing); err != nil {
+					return err
+				}
+				s.Empty(ing.Spec.TLS)
+				return nil
+			},
+		},
+		{
+			name: "Reconciler manages the HorizontalPodAutoscaler",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-autoscaling",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.WebFolder,
+						WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+							BaseURL: "https://example.com/assets/images",
+						},
+					},
+					Domains:     []string{"autoscaling.darkroom.net"},
+					Autoscaling: &deploymentsv1alpha1.Autoscaling{MaxReplicas: 5},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				if err := c.Create(ctx, d); err != nil {
+					return err
+				}
+				hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{}
+				s.Eventually(func() bool {
+					return c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, hpa) == nil
+				}, 10*time.Second, 250*time.Millisecond)
+				s.Equal(int32(5), hpa.Spec.MaxReplicas)
+
+				patch := client.MergeFrom(d.DeepCopy())
+				d.Spec.Autoscaling = nil
+				return c.Patch(ctx, d, patch)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{}
+				err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, hpa)
+				s.True(apierrors.IsNotFound(err))
+				return nil
+			},
+		},
+		{
+			name: "Reconciler manages the PodDisruptionBudget",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-disruption-budget",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.WebFolder,
+						WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+							BaseURL: "https://example.com/assets/images",
+						},
+					},
+					Domains:    []string{"disruption-budget.darkroom.net"},
+					Deployment: &deploymentsv1alpha1.Deployment{Replicas: &replicas},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				if err := c.Create(ctx, d); err != nil {
+					return err
+				}
+				pdb := &policyv1beta1.PodDisruptionBudget{}
+				s.Eventually(func() bool {
+					return c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, pdb) == nil
+				}, 10*time.Second, 250*time.Millisecond)
+				s.Equal(intstr.FromInt(1), *pdb.Spec.MaxUnavailable)
+
+				patch := client.MergeFrom(d.DeepCopy())
+				d.Spec.DisruptionBudget = &deploymentsv1alpha1.DisruptionBudget{Disabled: true}
+				return c.Patch(ctx, d, patch)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				pdb := &policyv1beta1.PodDisruptionBudget{}
+				err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, pdb)
+				s.True(apierrors.IsNotFound(err))
+				return nil
+			},
+		},
+		{
+			name: "Reconciler manages the NetworkPolicy",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-network-policy",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.WebFolder,
+						WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+							BaseURL: "https://example.com/assets/images",
+						},
+					},
+					Domains: []string{"network-policy.darkroom.net"},
+					NetworkPolicy: &deploymentsv1alpha1.NetworkPolicy{
+						IngressNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": "ingress-nginx"}},
+					},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				if err := c.Create(ctx, d); err != nil {
+					return err
+				}
+				policy := &networkingv1.NetworkPolicy{}
+				s.Eventually(func() bool {
+					return c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, policy) == nil
+				}, 10*time.Second, 250*time.Millisecond)
+				s.Equal(map[string]string{deploymentsv1alpha1.SelectorLabel: d.Name}, policy.Spec.PodSelector.MatchLabels)
+
+				patch := client.MergeFrom(d.DeepCopy())
+				d.Spec.NetworkPolicy = nil
+				return c.Patch(ctx, d, patch)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				policy := &networkingv1.NetworkPolicy{}
+				err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, policy)
+				s.True(apierrors.IsNotFound(err))
+				return nil
+			},
+		},
+		{
+			name: "Reconciler tears down the workloads of a deleted Darkroom",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-deletion-delete",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.WebFolder,
+						WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+							BaseURL: "https://example.com/assets/images",
+						},
+					},
+					Domains:        []string{"darkroom-deletion-delete.darkroom.net"},
+					DeletionPolicy: deploymentsv1alpha1.DeletionPolicyDelete,
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				if err := c.Create(ctx, d); err != nil {
+					return err
+				}
+				s.Eventually(func() bool {
+					depl := &appsv1.Deployment{}
+					if err := c.Get(ctx, client.ObjectKeyFromObject(d), depl); err != nil {
+						return false
+					}
+					if err := c.Get(ctx, client.ObjectKeyFromObject(d), d); err != nil {
+						return false
+					}
+					return controllerutil.ContainsFinalizer(d, deploymentsv1alpha1.CleanupFinalizer)
+				}, 10*time.Second, 250*time.Millisecond)
+				return c.Delete(ctx, d)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				err := c.Get(ctx, client.ObjectKeyFromObject(d), &deploymentsv1alpha1.Darkroom{})
+				if !apierrors.IsNotFound(err) {
+					return fmt.Errorf("darkroom is not deleted yet: %v", err)
+				}
+				err = c.Get(ctx, client.ObjectKeyFromObject(d), &networkingv1.Ingress{})
+				s.True(apierrors.IsNotFound(err))
+				err = c.Get(ctx, client.ObjectKeyFromObject(d), &appsv1.Deployment{})
+				s.True(apierrors.IsNotFound(err))
+				return nil
+			},
+		},
+		{
+			name: "Reconciler orphans the workloads of a deleted Darkroom",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-deletion-orphan",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.WebFolder,
+						WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+							BaseURL: "https://example.com/assets/images",
+						},
+					},
+					Domains:        []string{"darkroom-deletion-orphan.darkroom.net"},
+					DeletionPolicy: deploymentsv1alpha1.DeletionPolicyOrphan,
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				if err := c.Create(ctx, d); err != nil {
+					return err
+				}
+				s.Eventually(func() bool {
+					depl := &appsv1.Deployment{}
+					if err := c.Get(ctx, client.ObjectKeyFromObject(d), depl); err != nil {
+						return false
+					}
+					if err := c.Get(ctx, client.ObjectKeyFromObject(d), d); err != nil {
+						return false
+					}
+					return controllerutil.ContainsFinalizer(d, deploymentsv1alpha1.CleanupFinalizer)
+				}, 10*time.Second, 250*time.Millisecond)
+				return c.Delete(ctx, d)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				err := c.Get(ctx, client.ObjectKeyFromObject(d), &deploymentsv1alpha1.Darkroom{})
+				if !apierrors.IsNotFound(err) {
+					return fmt.Errorf("darkroom is not deleted yet: %v", err)
+				}
+				depl := &appsv1.Deployment{}
+				if err := c.Get(ctx, client.ObjectKeyFromObject(d), depl); err != nil {
+					return err
+				}
+				s.Empty(depl.OwnerReferences)
+				return nil
+			},
+		},
+		{
+			name: "Reconciler reverts and reports changes made outside of the operator",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-drift",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.WebFolder,
+						WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+							BaseURL: "https://example.com/assets/images",
+						},
+					},
+					Domains: []string{"drift.darkroom.net"},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				if err := c.Create(ctx, d); err != nil {
+					return err
+				}
+				cfg := &corev1.ConfigMap{}
+				s.Eventually(func() bool {
+					return c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, cfg) == nil
+				}, 10*time.Second, 250*time.Millisecond)
+
+				cfg.Data["METRICS_SYSTEM"] = "statsd"
+				return c.Update(ctx, cfg, client.FieldOwner("kubectl-edit"))
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				_, err := s.reconciler.Reconcile(ctx, ctrl.Request{
+					NamespacedName: types.NamespacedName{Namespace: d.Namespace, Name: d.Name},
+				})
+				s.NoError(err)
+
+				cfg := &corev1.ConfigMap{}
+				if err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, cfg); err != nil {
+					return err
+				}
+				s.Equal("prometheus", cfg.Data["METRICS_SYSTEM"])
+				s.GreaterOrEqual(testutil.ToFloat64(driftCorrections.WithLabelValues("ConfigMap")), float64(1))
+
+				var events []string
+				for len(s.recorder.Events) > 0 {
+					events = append(events, <-s.recorder.Events)
+				}
+				s.Contains(strings.Join(events, "\n"),
+					"Warning DriftCorrected Reverted changes to ConfigMap darkroom-drift made outside of the operator: .data.METRICS_SYSTEM")
+				return nil
+			},
+		},
+		{
+			name: "Reconciler leaves a paused Darkroom alone until it is resumed",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "darkroom-paused",
+					Namespace:   "default",
+					Annotations: map[string]string{deploymentsv1alpha1.PausedAnnotation: "true"},
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.WebFolder,
+						WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+							BaseURL: "https://example.com/assets/images",
+						},
+					},
+					Domains: []string{"paused.darkroom.net"},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				return c.Create(ctx, d)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				key := client.ObjectKey{Name: d.Name, Namespace: d.Namespace}
+				_, err := s.reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+				s.NoError(err)
+
+				current := &deploymentsv1alpha1.Darkroom{}
+				if err := c.Get(ctx, key, current); err != nil {
+					return err
+				}
+				s.True(meta.IsStatusConditionTrue(current.Status.Conditions, deploymentsv1alpha1.PausedCondition))
+				s.True(apierrors.IsNotFound(c.Get(ctx, key, &corev1.ConfigMap{})))
+
+				patch := client.MergeFrom(current.DeepCopy())
+				current.Annotations = map[string]string{deploymentsv1alpha1.ReconcileAtAnnotation: "2021-08-01T10:00:00Z"}
+				if err := c.Patch(ctx, current, patch); err != nil {
+					return err
+				}
+				_, err = s.reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+				s.NoError(err)
+
+				if err := c.Get(ctx, key, current); err != nil {
+					return err
+				}
+				s.False(meta.IsStatusConditionTrue(current.Status.Conditions, deploymentsv1alpha1.PausedCondition))
+				s.Equal("2021-08-01T10:00:00Z", current.Status.LastHandledReconcileAt)
+				return c.Get(ctx, key, &corev1.ConfigMap{})
+			},
+		},
+		{
+			name: "Reconciler rolls the pods when the configuration changes",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-config-hash",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.WebFolder,
+						WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{
+							BaseURL: "https://example.com/assets/images",
+						},
+					},
+					Domains: []string{"config-hash.darkroom.net"},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				if err := c.Create(ctx, d); err != nil {
+					return err
+				}
+				s.Eventually(func() bool {
+					current := &deploymentsv1alpha1.Darkroom{}
+					return c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, current) == nil &&
+						current.Status.ConfigHash != ""
+				}, 10*time.Second, 250*time.Millisecond)
+
+				patch := client.MergeFrom(d.DeepCopy())
+				d.Spec.Server = &deploymentsv1alpha1.Server{LogLevel: "debug"}
+				return c.Patch(ctx, d, patch)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				cfg := &corev1.ConfigMap{}
+				if err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, cfg); err != nil {
+					return err
+				}
+				s.Equal("debug", cfg.Data["LOG_LEVEL"])
+
+				depl := &appsv1.Deployment{}
+				if err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, depl); err != nil {
+					return err
+				}
+				s.Equal(configHash(*cfg), depl.Spec.Template.Annotations[configHashAnnotation])
+
+				desired := &deploymentsv1alpha1.Darkroom{}
+				if err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, desired); err != nil {
+					return err
+				}
+				s.Equal(configHash(*cfg), desired.Status.ConfigHash)
+				return nil
+			},
+		},
+		{
+			name: "Reconciler renders the S3 bucket into the ConfigMap",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-s3-config",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.S3,
+						Bucket: &deploymentsv1alpha1.Bucket{
+							Name:      "images",
+							Region:    "eu-west-1",
+							AccessKey: "some-key",
+							SecretKey: "super-secret",
+						},
+					},
+					Domains: []string{"darkroom-s3-config.darkroom.net"},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				return c.Create(ctx, d)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				cfgMap := &corev1.ConfigMap{}
+				desiredMap := map[string]string{
+					"CACHE_TIME":                            "31536000",
+					"DEBUG":                                 "false",
+					"LOG_LEVEL":                             "info",
+					"PORT":                                  "3000",
+					"SOURCE_BASEURL":                        "",
+					"SOURCE_BUCKET_INSECURESKIPVERIFY":      "false",
+					"SOURCE_BUCKET_NAME":                    "images",
+					"SOURCE_BUCKET_PATHSTYLE":               "false",
+					"SOURCE_BUCKET_REGION":                  "eu-west-1",
+					"SOURCE_HYSTRIX_COMMANDNAME":            "S3_ADAPTER",
+					"SOURCE_HYSTRIX_ERRORPERCENTTHRESHOLD":  "25",
+					"SOURCE_HYSTRIX_MAXCONCURRENTREQUESTS":  "100",
+					"SOURCE_HYSTRIX_REQUESTVOLUMETHRESHOLD": "10",
+					"SOURCE_HYSTRIX_SLEEPWINDOW":            "10",
+					"SOURCE_HYSTRIX_TIMEOUT":                "5000",
+					"SOURCE_KIND":                           "S3",
+				}
+				err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, cfgMap)
+				s.NoError(err)
+				s.Equal(desiredMap, cfgMap.Data)
+				return nil
+			},
+		},
+		{
+			name: "Reconciler renders a MinIO endpoint into the ConfigMap",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-minio-config",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.S3,
+						Bucket: &deploymentsv1alpha1.Bucket{
+							Name:               "images",
+							Region:             "us-east-1",
+							Endpoint:           "https://minio.storage.svc:9000",
+							PathStyle:          true,
+							InsecureSkipVerify: true,
+							AccessKey:          "some-key",
+							SecretKey:          "super-secret",
+						},
+					},
+					Domains: []string{"darkroom-minio-config.darkroom.net"},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				return c.Create(ctx, d)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				cfgMap := &corev1.ConfigMap{}
+				desiredMap := map[string]string{
+					"CACHE_TIME":                            "31536000",
+					"DEBUG":                                 "false",
+					"LOG_LEVEL":                             "info",
+					"PORT":                                  "3000",
+					"SOURCE_BASEURL":                        "",
+					"SOURCE_BUCKET_ENDPOINT":                "https://minio.storage.svc:9000",
+					"SOURCE_BUCKET_INSECURESKIPVERIFY":      "true",
+					"SOURCE_BUCKET_NAME":                    "images",
+					"SOURCE_BUCKET_PATHSTYLE":               "true",
+					"SOURCE_BUCKET_REGION":                  "us-east-1",
+					"SOURCE_HYSTRIX_COMMANDNAME":            "S3_ADAPTER",
+					"SOURCE_HYSTRIX_ERRORPERCENTTHRESHOLD":  "25",
+					"SOURCE_HYSTRIX_MAXCONCURRENTREQUESTS":  "100",
+					"SOURCE_HYSTRIX_REQUESTVOLUMETHRESHOLD": "10",
+					"SOURCE_HYSTRIX_SLEEPWINDOW":            "10",
+					"SOURCE_HYSTRIX_TIMEOUT":                "5000",
+					"SOURCE_KIND":                           "S3",
+				}
+				err := c.Get(ctx, client.ObjectKey{Name: d.Name, Namespace: d.Namespace}, cfgMap)
+				s.NoError(err)
+				s.Equal(desiredMap, cfgMap.Data)
+				return nil
+			},
+		},
+		{
+			name: "Reconciler reports the validated fallback sources",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-fallback",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type:          deploymentsv1alpha1.WebFolder,
+						WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					FallbackSources: []deploymentsv1alpha1.Source{
+						{
+							Type: deploymentsv1alpha1.S3,
+							Bucket: &deploymentsv1alpha1.Bucket{
+								Name:                 "images",
+								CredentialsSecretRef: &deploymentsv1alpha1.CredentialsSecretRef{Name: "darkroom-fallback-missing"},
+							},
+						},
+					},
+					Domains: []string{"darkroom-fallback.darkroom.net"},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				return c.Create(ctx, d)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				cfgMap := &corev1.ConfigMap{}
+				if err := c.Get(ctx, client.ObjectKeyFromObject(d), cfgMap); err != nil {
+					return err
+				}
+				// the fallback source is left out until its Secret can be read
+				s.NotContains(cfgMap.Data, "SOURCE_FALLBACKS")
+				s.NotContains(cfgMap.Data, "SOURCE_FALLBACK_0_BUCKET_NAME")
+
+				if err := c.Get(ctx, client.ObjectKeyFromObject(d), d); err != nil {
+					return err
+				}
+				if len(d.Status.Sources) != 2 {
+					return fmt.Errorf("expected 2 sources in status, got %d", len(d.Status.Sources))
+				}
+				s.True(d.Status.Sources[0].Valid)
+				s.False(d.Status.Sources[1].Valid)
+				s.Contains(d.Status.Sources[1].Message, "darkroom-fallback-missing")
+
+				return c.Get(ctx, client.ObjectKeyFromObject(d), &appsv1.Deployment{})
+			},
+		},
+		{
+			name: "Reconciler holds the ConfigMap and Deployment while the source Secrets can not be read",
+			ctx:  context.Background(),
+			darkroom: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "darkroom-held",
+					Namespace: "default",
+				},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.S3,
+						Bucket: &deploymentsv1alpha1.Bucket{
+							Name:                 "images",
+							CredentialsSecretRef: &deploymentsv1alpha1.CredentialsSecretRef{Name: "darkroom-held-missing"},
+						},
+					},
+					Domains: []string{"darkroom-held.darkroom.net"},
+				},
+			},
+			preReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				if err := c.Create(ctx, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: d.Name, Namespace: d.Namespace},
+					Data:       map[string]string{"SOURCE_KIND": "WebFolder"},
+				}); err != nil {
+					return err
+				}
+				return c.Create(ctx, d)
+			},
+			postReconcileRun: func(ctx context.Context, c client.Client, d *deploymentsv1alpha1.Darkroom) error {
+				if err := c.Get(ctx, client.ObjectKeyFromObject(d), d); err != nil {
+					return err
+				}
+				if !meta.IsStatusConditionFalse(d.Status.Conditions, deploymentsv1alpha1.SourceReachableCondition) {
+					return fmt.Errorf("expected the source to be unreachable")
+				}
+
+				cfgMap := &corev1.ConfigMap{}
+				if err := c.Get(ctx, client.ObjectKeyFromObject(d), cfgMap); err != nil {
+					return err
+				}
+				s.Equal(map[string]string{"SOURCE_KIND": "WebFolder"}, cfgMap.Data)
+				s.True(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(d), &appsv1.Deployment{})))
+				return nil
+			},
+		},
 	}
 
 	for _, t := range testcases {
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+
+	deploymentsv1alpha1 "github.com/gojekfarm/darkroom-operator/pkg/api/v1alpha1"
+)
+
+// the paths of the Darkroom webhooks, as configured by the markers on the v1alpha1 Darkroom
+const (
+	mutateDarkroomPath   = "/mutate-deployments-gojek-io-v1alpha1-darkroom"
+	validateDarkroomPath = "/validate-deployments-gojek-io-v1alpha1-darkroom"
+	convertPath          = "/convert"
+)
+
+// SetupWebhookWithManager registers the admission webhooks of the v1alpha1 Darkroom, along with the /convert
+// conversion webhook since the scheme holds its v1beta1 hub. Rejected requests are recorded as Events.
+func (r *DarkroomReconciler) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+	server := mgr.GetWebhookServer()
+	server.Register(mutateDarkroomPath, &webhook.Admission{Handler: &darkroomDefaulter{
+		decoder:  decoder,
+		imageTag: r.imageTag(),
+	}})
+	server.Register(validateDarkroomPath, &webhook.Admission{Handler: &darkroomValidator{
+		decoder:  decoder,
+		recorder: r.Recorder,
+	}})
+	server.Register(convertPath, &conversion.Webhook{})
+	return nil
+}
+
+// imageTag is what an empty or latest spec.version is resolved into
+func (r *DarkroomReconciler) imageTag() string {
+	if r.DefaultImageTag != "" {
+		return r.DefaultImageTag
+	}
+	return deploymentsv1alpha1.LatestVersion
+}
+
+// darkroomDefaulter defaults the Darkrooms of admission requests, resolving their version into imageTag
+type darkroomDefaulter struct {
+	decoder  *admission.Decoder
+	imageTag string
+}
+
+func (h *darkroomDefaulter) Handle(_ context.Context, req admission.Request) admission.Response {
+	var darkroom deploymentsv1alpha1.Darkroom
+	if err := h.decoder.Decode(req, &darkroom); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	darkroom.Default()
+	darkroom.ResolveVersion(h.imageTag)
+	defaulted, err := json.Marshal(darkroom)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, defaulted)
+}
+
+// darkroomValidator validates the Darkrooms of admission requests, recording the rejected ones as warning Events
+// on the Darkroom and counting the fields they were rejected for
+type darkroomValidator struct {
+	decoder  *admission.Decoder
+	recorder record.EventRecorder
+}
+
+func (h *darkroomValidator) Handle(_ context.Context, req admission.Request) admission.Response {
+	var darkroom, old deploymentsv1alpha1.Darkroom
+	var err error
+	reason := reasonValidationFailed
+	switch req.Operation {
+	case admissionv1.Create:
+		if err = h.decoder.DecodeRaw(req.Object, &darkroom); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = darkroom.ValidateCreate()
+	case admissionv1.Update:
+		if err = h.decoder.DecodeRaw(req.Object, &darkroom); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err = h.decoder.DecodeRaw(req.OldObject, &old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = darkroom.ValidateUpdate(&old)
+	case admissionv1.Delete:
+		// the OldObject of a delete request is the Darkroom being deleted
+		if err = h.decoder.DecodeRaw(req.OldObject, &darkroom); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = darkroom.ValidateDelete()
+		reason = reasonDeleteForbidden
+	}
+	if err == nil {
+		return admission.Allowed("")
+	}
+
+	h.recorder.Event(&darkroom, corev1.EventTypeWarning, reason, err.Error())
+	var apiStatus apierrors.APIStatus
+	if errors.As(err, &apiStatus) {
+		status := apiStatus.Status()
+		if status.Details != nil {
+			countRejections(darkroom.Namespace, status.Details.Causes)
+		}
+		return admission.Response{AdmissionResponse: admissionv1.AdmissionResponse{Allowed: false, Result: &status}}
+	}
+	return admission.Denied(err.Error())
+}
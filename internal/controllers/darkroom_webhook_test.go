@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	internalRuntime "github.com/gojekfarm/darkroom-operator/internal/runtime"
+	deploymentsv1alpha1 "github.com/gojekfarm/darkroom-operator/pkg/api/v1alpha1"
+)
+
+// admissionRequest builds the admission request of operation on obj, with old as the object it replaces
+func admissionRequest(t *testing.T, operation admissionv1.Operation, obj, old *deploymentsv1alpha1.Darkroom) admission.Request {
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: operation}}
+	if obj != nil {
+		raw, err := json.Marshal(obj)
+		assert.NoError(t, err)
+		req.Object = runtime.RawExtension{Raw: raw}
+	}
+	if old != nil {
+		raw, err := json.Marshal(old)
+		assert.NoError(t, err)
+		req.OldObject = runtime.RawExtension{Raw: raw}
+	}
+	return req
+}
+
+func TestDarkroomDefaulter(t *testing.T) {
+	decoder, err := admission.NewDecoder(internalRuntime.Scheme())
+	assert.NoError(t, err)
+	h := &darkroomDefaulter{decoder: decoder, imageTag: "0.1.0"}
+
+	for version, want := range map[string]interface{}{"": "0.1.0", "latest": "0.1.0", "0.0.9": nil} {
+		darkroom := &deploymentsv1alpha1.Darkroom{
+			TypeMeta:   metav1.TypeMeta{APIVersion: deploymentsv1alpha1.GroupVersion.String(), Kind: "Darkroom"},
+			ObjectMeta: metav1.ObjectMeta{Name: "darkroom", Namespace: "default"},
+			Spec:       deploymentsv1alpha1.DarkroomSpec{Version: version},
+		}
+		resp := h.Handle(context.Background(), admissionRequest(t, admissionv1.Create, darkroom, nil))
+
+		assert.True(t, resp.Allowed)
+		var got interface{}
+		for _, p := range resp.Patches {
+			if p.Path == "/spec/version" {
+				got = p.Value
+			}
+		}
+		assert.Equal(t, want, got, "version %q", version)
+	}
+}
+
+func TestDarkroomValidator(t *testing.T) {
+	decoder, err := admission.NewDecoder(internalRuntime.Scheme())
+	assert.NoError(t, err)
+	recorder := record.NewFakeRecorder(10)
+	h := &darkroomValidator{decoder: decoder, recorder: recorder}
+
+	valid := &deploymentsv1alpha1.Darkroom{
+		TypeMeta:   metav1.TypeMeta{APIVersion: deploymentsv1alpha1.GroupVersion.String(), Kind: "Darkroom"},
+		ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "default"},
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Source: deploymentsv1alpha1.Source{
+				Type:          deploymentsv1alpha1.WebFolder,
+				WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{BaseURL: "https://example.com"},
+			},
+		},
+	}
+	invalid := valid.DeepCopy()
+	invalid.Spec.Source.BaseURL = ""
+	protected := valid.DeepCopy()
+	protected.Annotations = map[string]string{deploymentsv1alpha1.ProtectedAnnotation: "true"}
+
+	resp := h.Handle(context.Background(), admissionRequest(t, admissionv1.Create, valid, nil))
+	assert.True(t, resp.Allowed)
+	resp = h.Handle(context.Background(), admissionRequest(t, admissionv1.Update, protected, valid))
+	assert.True(t, resp.Allowed)
+	assert.Empty(t, recorder.Events)
+
+	rejections := validationRejections.WithLabelValues("default", "spec.source.baseUrl")
+	before := testutil.ToFloat64(rejections)
+	resp = h.Handle(context.Background(), admissionRequest(t, admissionv1.Create, invalid, nil))
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, metav1.StatusReasonInvalid, resp.Result.Reason)
+	assert.Equal(t, float64(1), testutil.ToFloat64(rejections)-before)
+	assert.Contains(t, <-recorder.Events, `Warning ValidationFailed Darkroom.deployments.gojek.io "sample" is invalid`)
+
+	resp = h.Handle(context.Background(), admissionRequest(t, admissionv1.Delete, nil, protected))
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, metav1.StatusReasonForbidden, resp.Result.Reason)
+	assert.Contains(t, <-recorder.Events, "Warning DeleteForbidden")
+	assert.Empty(t, recorder.Events)
+}
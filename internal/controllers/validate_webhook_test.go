@@ -61,6 +61,22 @@ func (s *DarkroomControllerSuite) TestCreate() {
 				},
 			},
 		},
+		{
+			name: "S3WithCredentialsSecretRef",
+			obj: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.S3,
+						Bucket: &deploymentsv1alpha1.Bucket{
+							Name:                 "abc-test",
+							CredentialsSecretRef: &deploymentsv1alpha1.CredentialsSecretRef{Name: "abc-credentials"},
+						},
+					},
+					Domains: []string{"test.darkroom.com"},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -213,6 +229,41 @@ func (s *DarkroomControllerSuite) TestCreateError() {
 			},
 			errString: `spec.source.bucket.credentialsJson: Invalid value: "{some-bad-json-data}"`,
 		},
+		{
+			name: "FailS3WithCredentialsSecretRefAndInlineCredentials",
+			obj: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type: deploymentsv1alpha1.S3,
+						Bucket: &deploymentsv1alpha1.Bucket{
+							Name:                 "test-bucket",
+							AccessKey:            "some-key",
+							CredentialsSecretRef: &deploymentsv1alpha1.CredentialsSecretRef{Name: "test-credentials"},
+						},
+					},
+					Domains: []string{"test.darkroom.com"},
+				},
+			},
+			errString: `spec.source.bucket.credentialsSecretRef: Forbidden: may not be set along with inline credentials`,
+		},
+		{
+			name: "FailWebFolderWithReservedPodLabel",
+			obj: &deploymentsv1alpha1.Darkroom{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec: deploymentsv1alpha1.DarkroomSpec{
+					Source: deploymentsv1alpha1.Source{
+						Type:          deploymentsv1alpha1.WebFolder,
+						WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Domains: []string{"test.darkroom.com"},
+					Deployment: &deploymentsv1alpha1.Deployment{
+						PodLabels: map[string]string{"darkroom": "other"},
+					},
+				},
+			},
+			errString: `spec.deployment.podLabels[darkroom]: Forbidden: label is reserved for the pod selector`,
+		},
 	}
 
 	for _, tc := range testcases {
@@ -224,3 +275,62 @@ func (s *DarkroomControllerSuite) TestCreateError() {
 		})
 	}
 }
+
+func (s *DarkroomControllerSuite) TestUpdateError() {
+	ctx := context.Background()
+	d := &deploymentsv1alpha1.Darkroom{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-update", Namespace: "default"},
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Source: deploymentsv1alpha1.Source{
+				Type: deploymentsv1alpha1.S3,
+				Bucket: &deploymentsv1alpha1.Bucket{
+					Name:      "abc-test",
+					AccessKey: "some-key",
+					SecretKey: "super-secret",
+				},
+			},
+			Domains: []string{"test-update.darkroom.com"},
+		},
+	}
+	s.NoError(s.client.Create(ctx, d))
+	defer s.client.Delete(ctx, d)
+
+	d.Spec.Source = deploymentsv1alpha1.Source{
+		Type:          deploymentsv1alpha1.WebFolder,
+		WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{BaseURL: "https://example.com"},
+	}
+	err := s.client.Update(ctx, d, client.DryRunAll)
+	s.Error(err)
+	s.Contains(err.Error(), "spec.source.type: Forbidden: field is immutable")
+
+	d.Annotations = map[string]string{deploymentsv1alpha1.MigrateSourceAnnotation: "true"}
+	s.NoError(s.client.Update(ctx, d, client.DryRunAll))
+}
+
+func (s *DarkroomControllerSuite) TestDeleteProtected() {
+	ctx := context.Background()
+	d := &deploymentsv1alpha1.Darkroom{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-protected",
+			Namespace:   "default",
+			Annotations: map[string]string{deploymentsv1alpha1.ProtectedAnnotation: "true"},
+		},
+		Spec: deploymentsv1alpha1.DarkroomSpec{
+			Source: deploymentsv1alpha1.Source{
+				Type:          deploymentsv1alpha1.WebFolder,
+				WebFolderMeta: deploymentsv1alpha1.WebFolderMeta{BaseURL: "https://example.com"},
+			},
+			Domains: []string{"test-protected.darkroom.com"},
+		},
+	}
+	s.NoError(s.client.Create(ctx, d))
+
+	err := s.client.Delete(ctx, d)
+	s.Error(err)
+	s.Contains(err.Error(), "the Darkroom is protected")
+
+	patch := client.MergeFrom(d.DeepCopy())
+	d.Annotations = nil
+	s.NoError(s.client.Patch(ctx, d, patch))
+	s.NoError(s.client.Delete(ctx, d))
+}
@@ -6,6 +6,7 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 
 	deploymentsv1alpha1 "github.com/gojekfarm/darkroom-operator/pkg/api/v1alpha1"
+	deploymentsv1beta1 "github.com/gojekfarm/darkroom-operator/pkg/api/v1beta1"
 )
 
 var scheme = runtime.NewScheme()
@@ -14,6 +15,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(deploymentsv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(deploymentsv1beta1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
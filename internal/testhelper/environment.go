@@ -41,6 +41,7 @@ type Environment interface {
 	GetLogger() logr.Logger
 	ResetLogs()
 	WithWaitOptions(...WaitOption) Environment
+	WithCRDPaths(...string) Environment
 }
 
 type WebhookWaitOption bool
@@ -167,6 +168,13 @@ func (e *env) WithWaitOptions(options ...WaitOption) Environment {
 	return e
 }
 
+// WithCRDPaths installs the CRDs found in paths next to the ones of the operator,
+// e.g. to run against optional third party APIs
+func (e *env) WithCRDPaths(paths ...string) Environment {
+	e.k8sEnv.CRDInstallOptions.Paths = append(e.k8sEnv.CRDInstallOptions.Paths, paths...)
+	return e
+}
+
 func NewTestEnvironment(dirElems ...string) Environment {
 	b := &bytes.Buffer{}
 	l := zap.New(zap.UseDevMode(true), zap.WriteTo(b), zap.Level(zapcore.DebugLevel))
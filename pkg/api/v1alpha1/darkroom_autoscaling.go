@@ -0,0 +1,73 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Autoscaling scales the darkroom pods with a HorizontalPodAutoscaler. While it is set,
+// spec.deployment.replicas is left to the autoscaler.
+type Autoscaling struct {
+	// MinReplicas is the lower limit of darkroom pods, defaults to 1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// MaxReplicas is the upper limit of darkroom pods
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+	// TargetCPUUtilizationPercentage is the average CPU utilization of the darkroom pods,
+	// relative to their requests
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+	// TargetMemoryUtilizationPercentage is the average memory utilization of the darkroom pods,
+	// relative to their requests
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+	// CustomMetrics are per-pod metrics served by a custom metrics API
+	// +optional
+	CustomMetrics []CustomMetric `json:"customMetrics,omitempty"`
+}
+
+// CustomMetric targets an average value of a metric describing the darkroom pods
+type CustomMetric struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// TargetAverageValue is the value of the metric averaged across the darkroom pods
+	TargetAverageValue resource.Quantity `json:"targetAverageValue"`
+}
+
+func (d *Darkroom) validateAutoscaling() field.ErrorList {
+	a := d.Spec.Autoscaling
+	if a == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	path := field.NewPath("spec").Child("autoscaling")
+	if a.MaxReplicas < 1 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxReplicas"), a.MaxReplicas, "must be greater than or equal to 1"))
+	}
+	if a.MinReplicas != nil && *a.MinReplicas > a.MaxReplicas {
+		allErrs = append(allErrs, field.Invalid(path.Child("minReplicas"), *a.MinReplicas, "must be less than or equal to maxReplicas"))
+	}
+	if p := a.TargetCPUUtilizationPercentage; p != nil && *p < 1 {
+		allErrs = append(allErrs, field.Invalid(path.Child("targetCPUUtilizationPercentage"), *p, "must be greater than 0"))
+	}
+	if p := a.TargetMemoryUtilizationPercentage; p != nil && *p < 1 {
+		allErrs = append(allErrs, field.Invalid(path.Child("targetMemoryUtilizationPercentage"), *p, "must be greater than 0"))
+	}
+	for i, m := range a.CustomMetrics {
+		if m.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("customMetrics").Index(i).Child("name"), "can not be empty"))
+		}
+		if m.TargetAverageValue.Sign() <= 0 {
+			allErrs = append(allErrs, field.Invalid(
+				path.Child("customMetrics").Index(i).Child("targetAverageValue"),
+				m.TargetAverageValue.String(),
+				"must be greater than 0",
+			))
+		}
+	}
+	return allErrs
+}
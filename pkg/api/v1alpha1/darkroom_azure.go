@@ -0,0 +1,76 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	AzureBlobStorage Type = "AzureBlobStorage"
+)
+
+var (
+	azureAccountNameRegexp = regexp.MustCompile(`^[a-z0-9]{3,24}$`)
+	azureContainerRegexp   = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+)
+
+// AzureBlob locates the images in a container of an Azure storage account
+type AzureBlob struct {
+	// AccountName of the Azure storage account
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]{3,24}$`
+	AccountName string `json:"accountName"`
+	// Container of the storage account holding the images
+	// +kubebuilder:validation:MinLength=3
+	// +kubebuilder:validation:MaxLength=63
+	Container string `json:"container"`
+	// SASToken is readable by anyone who can read the Darkroom, prefer CredentialsSecretRef
+	// +optional
+	SASToken string `json:"sasToken,omitempty"`
+	// CredentialsSecretRef references a Secret in the Darkroom namespace holding the SAS token
+	// +optional
+	CredentialsSecretRef *CredentialsSecretRef `json:"credentialsSecretRef,omitempty"`
+}
+
+func (s *Source) validateAzureBlobStorage(path *field.Path) field.ErrorList {
+	if err := s.validateNoWebFolderMeta(path); err != nil {
+		return field.ErrorList{err}
+	}
+	if s.Bucket != nil {
+		return field.ErrorList{field.Forbidden(
+			path.Child("bucket"),
+			fmt.Sprintf("may not be set with Type %s", s.Type),
+		)}
+	}
+	a := s.Azure
+	if a == nil {
+		return field.ErrorList{field.Required(
+			path.Child("azure"),
+			fmt.Sprintf("field required with Type %s", s.Type),
+		)}
+	}
+	path = path.Child("azure")
+
+	var allErrs field.ErrorList
+	if !azureAccountNameRegexp.MatchString(a.AccountName) {
+		allErrs = append(allErrs, field.Invalid(path.Child("accountName"), a.AccountName,
+			"must be 3 to 24 lower case alphanumeric characters"))
+	}
+	if len(a.Container) < 3 || len(a.Container) > 63 || !azureContainerRegexp.MatchString(a.Container) {
+		allErrs = append(allErrs, field.Invalid(path.Child("container"), a.Container,
+			"must be 3 to 63 lower case alphanumeric characters or single '-' between them"))
+	}
+	switch {
+	case a.CredentialsSecretRef != nil:
+		if err := validateCredentialsSecretRef(path, a.CredentialsSecretRef, a.SASToken != ""); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	case a.SASToken == "":
+		allErrs = append(allErrs, field.Required(
+			path.Child("sasToken"),
+			fmt.Sprintf("sasToken or credentialsSecretRef required with Type %s", s.Type),
+		))
+	}
+	return allErrs
+}
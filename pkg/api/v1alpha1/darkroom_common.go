@@ -1,10 +1,138 @@
 package v1alpha1
 
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	DefaultAccessKeyKey       = "accessKey"
+	DefaultSecretKeyKey       = "secretKey"
+	DefaultCredentialsJsonKey = "credentialsJson"
+	DefaultSASTokenKey        = "sasToken"
+	DefaultUsernameKey        = "username"
+	DefaultPasswordKey        = "password"
+)
+
 type Bucket struct {
 	// +kubebuilder:validation:MinLength=3
 	Name string `json:"name"`
-
+	// Deprecated: CredentialsJson is readable by anyone who can read the Darkroom, use CredentialsSecretRef instead
 	CredentialsJson string `json:"credentialsJson,omitempty"`
-	AccessKey       string `json:"accessKey,omitempty"`
-	SecretKey       string `json:"secretKey,omitempty"`
+	// Deprecated: AccessKey is readable by anyone who can read the Darkroom, use CredentialsSecretRef instead
+	AccessKey string `json:"accessKey,omitempty"`
+	// Deprecated: SecretKey is readable by anyone who can read the Darkroom, use CredentialsSecretRef instead
+	SecretKey string `json:"secretKey,omitempty"`
+	// CredentialsSecretRef references a Secret in the Darkroom namespace holding the bucket credentials
+	// +optional
+	CredentialsSecretRef *CredentialsSecretRef `json:"credentialsSecretRef,omitempty"`
+	// Region of the S3 bucket
+	// +kubebuilder:validation:Pattern=`^[a-z0-9-]+$`
+	// +optional
+	Region string `json:"region,omitempty"`
+	// Endpoint is the URL of an S3-compatible service, e.g. MinIO or Ceph, instead of AWS
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+	// PathStyle addresses the S3 bucket in the path of the request URL instead of its host
+	// +optional
+	PathStyle bool `json:"pathStyle,omitempty"`
+	// InsecureSkipVerify skips the verification of the certificate served by Endpoint
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// CredentialsSecretRef maps the bucket credentials to keys of a Secret
+type CredentialsSecretRef struct {
+	// Name of the Secret in the Darkroom namespace
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// AccessKeyKey is the key of the Secret holding the S3 access key
+	// +kubebuilder:default=accessKey
+	// +optional
+	AccessKeyKey string `json:"accessKeyKey,omitempty"`
+	// SecretKeyKey is the key of the Secret holding the S3 secret key
+	// +kubebuilder:default=secretKey
+	// +optional
+	SecretKeyKey string `json:"secretKeyKey,omitempty"`
+	// CredentialsJsonKey is the key of the Secret holding the GoogleCloudStorage credentials json
+	// +kubebuilder:default=credentialsJson
+	// +optional
+	CredentialsJsonKey string `json:"credentialsJsonKey,omitempty"`
+	// SASTokenKey is the key of the Secret holding the AzureBlobStorage SAS token
+	// +kubebuilder:default=sasToken
+	// +optional
+	SASTokenKey string `json:"sasTokenKey,omitempty"`
+}
+
+func (r *CredentialsSecretRef) Default() {
+	if r.AccessKeyKey == "" {
+		r.AccessKeyKey = DefaultAccessKeyKey
+	}
+	if r.SecretKeyKey == "" {
+		r.SecretKeyKey = DefaultSecretKeyKey
+	}
+	if r.CredentialsJsonKey == "" {
+		r.CredentialsJsonKey = DefaultCredentialsJsonKey
+	}
+	if r.SASTokenKey == "" {
+		r.SASTokenKey = DefaultSASTokenKey
+	}
+}
+
+// HasInlineCredentials reports whether any of the deprecated inline credential fields are set
+func (b *Bucket) HasInlineCredentials() bool {
+	return b.AccessKey != "" || b.SecretKey != "" || b.CredentialsJson != ""
+}
+
+// CredentialsSecretRef returns the CredentialsSecretRef of the bucket or Azure container of the source
+func (s *Source) CredentialsSecretRef() *CredentialsSecretRef {
+	switch {
+	case s.Bucket != nil:
+		return s.Bucket.CredentialsSecretRef
+	case s.Azure != nil:
+		return s.Azure.CredentialsSecretRef
+	}
+	return nil
+}
+
+// HasInlineCredentials reports whether the bucket or Azure container of the source has inline credentials
+func (s *Source) HasInlineCredentials() bool {
+	switch {
+	case s.Bucket != nil:
+		return s.Bucket.HasInlineCredentials()
+	case s.Azure != nil:
+		return s.Azure.SASToken != ""
+	}
+	return false
+}
+
+// SecretNames returns the names of the Secrets referenced by the source, e.g. by CredentialsSecretRef or Headers
+func (s *Source) SecretNames() []string {
+	var names []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if ref := s.CredentialsSecretRef(); ref != nil {
+		add(ref.Name)
+	}
+	for _, name := range s.HeaderNames() {
+		add(s.Headers[name].Name)
+	}
+	if s.BasicAuthSecretRef != nil {
+		add(s.BasicAuthSecretRef.Name)
+	}
+	return names
+}
+
+func validateCredentialsSecretRef(path *field.Path, ref *CredentialsSecretRef, inline bool) *field.Error {
+	if inline {
+		return field.Forbidden(path.Child("credentialsSecretRef"), "may not be set along with inline credentials")
+	}
+	if ref.Name == "" {
+		return field.Required(path.Child("credentialsSecretRef").Child("name"), "name of the Secret is required")
+	}
+	return nil
 }
@@ -0,0 +1,402 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/gojekfarm/darkroom-operator/pkg/api/v1beta1"
+)
+
+const (
+	// v1alpha1ConversionDataAnnotation keeps the fields of a v1alpha1 Darkroom that v1beta1 can not represent
+	// on the converted v1beta1 Darkroom so that converting it back is lossless. Inline credentials are never
+	// kept, a Darkroom with them is not converted until they are moved to a credentialsSecretRef.
+	v1alpha1ConversionDataAnnotation = "deployments.gojek.io/v1alpha1-conversion-data"
+	// v1beta1ConversionDataAnnotation keeps the fields of a v1beta1 Darkroom that v1alpha1 can not represent
+	v1beta1ConversionDataAnnotation = "deployments.gojek.io/v1beta1-conversion-data"
+)
+
+var _ conversion.Convertible = &Darkroom{}
+
+// conversionData holds the fields of the sources that the other API version can not represent,
+// indexed like the source followed by the fallback sources
+type conversionData struct {
+	Sources []sourceConversionData `json:"sources"`
+}
+
+type sourceConversionData struct {
+	// AzureCredentialsSecretRef is set when a v1alpha1 source references Secrets from both bucket and azure
+	AzureCredentialsSecretRef *CredentialsSecretRef `json:"azureCredentialsSecretRef,omitempty"`
+
+	// CredentialsSecretRef is set when a v1beta1 source references a Secret without a bucket or azure
+	CredentialsSecretRef *v1beta1.CredentialsSecretRef `json:"credentialsSecretRef,omitempty"`
+	// WebFolder is set when a v1beta1 source has an empty webFolder
+	WebFolder bool `json:"webFolder,omitempty"`
+}
+
+// ConvertTo converts this Darkroom to the v1beta1 hub
+func (src *Darkroom) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.Darkroom)
+	if err := src.validateConvertibleCredentials(); err != nil {
+		return err
+	}
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	var restore conversionData
+	if err := popConversionData(&dst.ObjectMeta, v1beta1ConversionDataAnnotation, &restore); err != nil {
+		return err
+	}
+	data := conversionData{Sources: make([]sourceConversionData, 1+len(src.Spec.FallbackSources))}
+
+	in, out := &src.Spec, &dst.Spec
+	out.Version = in.Version
+	out.Image = (*v1beta1.Image)(in.Image.DeepCopy())
+	out.Source = convertSourceTo(&in.Source, &data.Sources[0], restore.source(0))
+	out.FallbackSources = nil
+	if in.FallbackSources != nil {
+		out.FallbackSources = make([]v1beta1.Source, len(in.FallbackSources))
+		for i := range in.FallbackSources {
+			out.FallbackSources[i] = convertSourceTo(&in.FallbackSources[i], &data.Sources[i+1], restore.source(i+1))
+		}
+	}
+	out.PathPrefix = in.PathPrefix
+	out.Domains = in.Domains
+	out.Server = (*v1beta1.Server)(in.Server.DeepCopy())
+	out.ExtraEnv = in.ExtraEnv
+	out.Deployment = convertDeploymentTo(in.Deployment.DeepCopy())
+	out.Autoscaling = nil
+	if a := in.Autoscaling; a != nil {
+		out.Autoscaling = &v1beta1.Autoscaling{
+			MinReplicas:                       a.MinReplicas,
+			MaxReplicas:                       a.MaxReplicas,
+			TargetCPUUtilizationPercentage:    a.TargetCPUUtilizationPercentage,
+			TargetMemoryUtilizationPercentage: a.TargetMemoryUtilizationPercentage,
+		}
+		if a.CustomMetrics != nil {
+			out.Autoscaling.CustomMetrics = make([]v1beta1.CustomMetric, len(a.CustomMetrics))
+			for i, m := range a.CustomMetrics {
+				out.Autoscaling.CustomMetrics[i] = v1beta1.CustomMetric(m)
+			}
+		}
+	}
+	out.DisruptionBudget = (*v1beta1.DisruptionBudget)(in.DisruptionBudget.DeepCopy())
+	out.TLS = nil
+	if t := in.TLS; t != nil {
+		out.TLS = &v1beta1.TLS{IssuerRef: v1beta1.IssuerRef(t.IssuerRef), SecretName: t.SecretName}
+	}
+	out.Ingress = nil
+	if i := in.Ingress; i != nil {
+		out.Ingress = &v1beta1.Ingress{
+			ClassName:     i.ClassName,
+			Annotations:   i.Annotations,
+			TLSSecretName: i.TLSSecretName,
+			GatewayRef:    (*v1beta1.GatewayRef)(i.GatewayRef.DeepCopy()),
+		}
+	}
+	out.NetworkPolicy = (*v1beta1.NetworkPolicy)(in.NetworkPolicy.DeepCopy())
+	out.ServiceMonitor = (*v1beta1.ServiceMonitor)(in.ServiceMonitor.DeepCopy())
+	out.DeletionPolicy = v1beta1.DeletionPolicy(in.DeletionPolicy)
+
+	status, outStatus := &src.Status, &dst.Status
+	*outStatus = v1beta1.DarkroomStatus{
+		DeployState:            v1beta1.DeployState(status.DeployState),
+		Domains:                status.Domains,
+		ObservedGeneration:     status.ObservedGeneration,
+		Replicas:               status.Replicas,
+		UpdatedReplicas:        status.UpdatedReplicas,
+		ReadyReplicas:          status.ReadyReplicas,
+		AvailableReplicas:      status.AvailableReplicas,
+		ConfigHash:             status.ConfigHash,
+		Selector:               status.Selector,
+		LastHandledReconcileAt: status.LastHandledReconcileAt,
+		Conditions:             status.Conditions,
+	}
+	if status.Sources != nil {
+		outStatus.Sources = make([]v1beta1.SourceStatus, len(status.Sources))
+		for i, s := range status.Sources {
+			outStatus.Sources[i] = v1beta1.SourceStatus{Path: s.Path, Type: v1beta1.Type(s.Type), Valid: s.Valid, Message: s.Message}
+		}
+	}
+
+	return pushConversionData(&dst.ObjectMeta, v1alpha1ConversionDataAnnotation, data)
+}
+
+// ConvertFrom converts the v1beta1 hub to this Darkroom
+func (dst *Darkroom) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.Darkroom)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	var restore conversionData
+	if err := popConversionData(&dst.ObjectMeta, v1alpha1ConversionDataAnnotation, &restore); err != nil {
+		return err
+	}
+	data := conversionData{Sources: make([]sourceConversionData, 1+len(src.Spec.FallbackSources))}
+
+	in, out := &src.Spec, &dst.Spec
+	out.Version = in.Version
+	out.Image = (*Image)(in.Image.DeepCopy())
+	out.Source = convertSourceFrom(&in.Source, &data.Sources[0], restore.source(0))
+	out.FallbackSources = nil
+	if in.FallbackSources != nil {
+		out.FallbackSources = make([]Source, len(in.FallbackSources))
+		for i := range in.FallbackSources {
+			out.FallbackSources[i] = convertSourceFrom(&in.FallbackSources[i], &data.Sources[i+1], restore.source(i+1))
+		}
+	}
+	out.PathPrefix = in.PathPrefix
+	out.Domains = in.Domains
+	out.Server = (*Server)(in.Server.DeepCopy())
+	out.ExtraEnv = in.ExtraEnv
+	out.Deployment = convertDeploymentFrom(in.Deployment.DeepCopy())
+	out.Autoscaling = nil
+	if a := in.Autoscaling; a != nil {
+		out.Autoscaling = &Autoscaling{
+			MinReplicas:                       a.MinReplicas,
+			MaxReplicas:                       a.MaxReplicas,
+			TargetCPUUtilizationPercentage:    a.TargetCPUUtilizationPercentage,
+			TargetMemoryUtilizationPercentage: a.TargetMemoryUtilizationPercentage,
+		}
+		if a.CustomMetrics != nil {
+			out.Autoscaling.CustomMetrics = make([]CustomMetric, len(a.CustomMetrics))
+			for i, m := range a.CustomMetrics {
+				out.Autoscaling.CustomMetrics[i] = CustomMetric(m)
+			}
+		}
+	}
+	out.DisruptionBudget = (*DisruptionBudget)(in.DisruptionBudget.DeepCopy())
+	out.TLS = nil
+	if t := in.TLS; t != nil {
+		out.TLS = &TLS{IssuerRef: IssuerRef(t.IssuerRef), SecretName: t.SecretName}
+	}
+	out.Ingress = nil
+	if i := in.Ingress; i != nil {
+		out.Ingress = &Ingress{
+			ClassName:     i.ClassName,
+			Annotations:   i.Annotations,
+			TLSSecretName: i.TLSSecretName,
+			GatewayRef:    (*GatewayRef)(i.GatewayRef.DeepCopy()),
+		}
+	}
+	out.NetworkPolicy = (*NetworkPolicy)(in.NetworkPolicy.DeepCopy())
+	out.ServiceMonitor = (*ServiceMonitor)(in.ServiceMonitor.DeepCopy())
+	out.DeletionPolicy = DeletionPolicy(in.DeletionPolicy)
+
+	status, outStatus := &src.Status, &dst.Status
+	*outStatus = DarkroomStatus{
+		DeployState:            DeployState(status.DeployState),
+		Domains:                status.Domains,
+		ObservedGeneration:     status.ObservedGeneration,
+		Replicas:               status.Replicas,
+		UpdatedReplicas:        status.UpdatedReplicas,
+		ReadyReplicas:          status.ReadyReplicas,
+		AvailableReplicas:      status.AvailableReplicas,
+		ConfigHash:             status.ConfigHash,
+		Selector:               status.Selector,
+		LastHandledReconcileAt: status.LastHandledReconcileAt,
+		Conditions:             status.Conditions,
+	}
+	if status.Sources != nil {
+		outStatus.Sources = make([]SourceStatus, len(status.Sources))
+		for i, s := range status.Sources {
+			outStatus.Sources[i] = SourceStatus{Path: s.Path, Type: Type(s.Type), Valid: s.Valid, Message: s.Message}
+		}
+	}
+
+	return pushConversionData(&dst.ObjectMeta, v1beta1ConversionDataAnnotation, data)
+}
+
+func convertDeploymentTo(in *Deployment) *v1beta1.Deployment {
+	if in == nil {
+		return nil
+	}
+	return &v1beta1.Deployment{
+		Replicas:                  in.Replicas,
+		Resources:                 in.Resources,
+		NodeSelector:              in.NodeSelector,
+		Tolerations:               in.Tolerations,
+		Affinity:                  in.Affinity,
+		TopologySpreadConstraints: in.TopologySpreadConstraints,
+		PriorityClassName:         in.PriorityClassName,
+		PodAnnotations:            in.PodAnnotations,
+		PodLabels:                 in.PodLabels,
+		Probes:                    (*v1beta1.Probes)(in.Probes),
+		RollingUpdate:             in.RollingUpdate,
+	}
+}
+
+func convertDeploymentFrom(in *v1beta1.Deployment) *Deployment {
+	if in == nil {
+		return nil
+	}
+	return &Deployment{
+		Replicas:                  in.Replicas,
+		Resources:                 in.Resources,
+		NodeSelector:              in.NodeSelector,
+		Tolerations:               in.Tolerations,
+		Affinity:                  in.Affinity,
+		TopologySpreadConstraints: in.TopologySpreadConstraints,
+		PriorityClassName:         in.PriorityClassName,
+		PodAnnotations:            in.PodAnnotations,
+		PodLabels:                 in.PodLabels,
+		Probes:                    (*Probes)(in.Probes),
+		RollingUpdate:             in.RollingUpdate,
+	}
+}
+
+// validateConvertibleCredentials refuses converting a Darkroom whose sources have inline credentials, which
+// v1beta1 can not represent and which must not be carried along in an annotation
+func (d *Darkroom) validateConvertibleCredentials() error {
+	var allErrs field.ErrorList
+	check := func(s Source, path *field.Path) {
+		if s.Bucket != nil && s.Bucket.HasInlineCredentials() {
+			allErrs = append(allErrs, field.Forbidden(path.Child("bucket"),
+				"inline credentials can not be converted to v1beta1, move them to credentialsSecretRef first"))
+		}
+		if s.Azure != nil && s.Azure.SASToken != "" {
+			allErrs = append(allErrs, field.Forbidden(path.Child("azure").Child("sasToken"),
+				"inline credentials can not be converted to v1beta1, move them to credentialsSecretRef first"))
+		}
+	}
+	check(d.Spec.Source, field.NewPath("spec").Child("source"))
+	for i, s := range d.Spec.FallbackSources {
+		check(s, field.NewPath("spec").Child("fallbackSources").Index(i))
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "Darkroom"}, d.Name, allErrs)
+}
+
+// convertSourceTo converts in to v1beta1, recording the fields v1beta1 can not represent in data
+// and restoring the v1beta1 fields recorded in restore
+func convertSourceTo(in *Source, data, restore *sourceConversionData) v1beta1.Source {
+	out := v1beta1.Source{
+		Type:           v1beta1.Type(in.Type),
+		Prefix:         in.Prefix,
+		CircuitBreaker: (*v1beta1.CircuitBreaker)(in.CircuitBreaker.DeepCopy()),
+	}
+	if w := in.WebFolderMeta; w.BaseURL != "" || w.Headers != nil || w.BasicAuthSecretRef != nil || restore.WebFolder {
+		out.WebFolder = &v1beta1.WebFolderSource{
+			BaseURL:            w.BaseURL,
+			BasicAuthSecretRef: (*v1beta1.BasicAuthSecretRef)(w.BasicAuthSecretRef.DeepCopy()),
+		}
+		if w.Headers != nil {
+			out.WebFolder.Headers = make(map[string]v1beta1.SecretKeyRef, len(w.Headers))
+			for k, v := range w.Headers {
+				out.WebFolder.Headers[k] = v1beta1.SecretKeyRef(v)
+			}
+		}
+	}
+	if a := in.Azure; a != nil {
+		out.Azure = &v1beta1.AzureBlob{AccountName: a.AccountName, Container: a.Container}
+		out.CredentialsSecretRef = (*v1beta1.CredentialsSecretRef)(a.CredentialsSecretRef.DeepCopy())
+	}
+	if b := in.Bucket; b != nil {
+		out.Bucket = &v1beta1.Bucket{
+			Name:               b.Name,
+			Region:             b.Region,
+			Endpoint:           b.Endpoint,
+			PathStyle:          b.PathStyle,
+			InsecureSkipVerify: b.InsecureSkipVerify,
+		}
+		if in.Azure != nil {
+			data.AzureCredentialsSecretRef = in.Azure.CredentialsSecretRef.DeepCopy()
+		}
+		out.CredentialsSecretRef = (*v1beta1.CredentialsSecretRef)(b.CredentialsSecretRef.DeepCopy())
+	}
+	if out.Bucket == nil && out.Azure == nil {
+		out.CredentialsSecretRef = restore.CredentialsSecretRef.DeepCopy()
+	}
+	return out
+}
+
+// convertSourceFrom converts in from v1beta1, recording the fields v1alpha1 can not represent in data
+// and restoring the v1alpha1 fields recorded in restore
+func convertSourceFrom(in *v1beta1.Source, data, restore *sourceConversionData) Source {
+	out := Source{
+		Type:           Type(in.Type),
+		Prefix:         in.Prefix,
+		CircuitBreaker: (*CircuitBreaker)(in.CircuitBreaker.DeepCopy()),
+	}
+	if w := in.WebFolder; w != nil {
+		out.BaseURL = w.BaseURL
+		out.BasicAuthSecretRef = (*BasicAuthSecretRef)(w.BasicAuthSecretRef.DeepCopy())
+		if w.Headers != nil {
+			out.Headers = make(map[string]SecretKeyRef, len(w.Headers))
+			for k, v := range w.Headers {
+				out.Headers[k] = SecretKeyRef(v)
+			}
+		}
+		data.WebFolder = out.BaseURL == "" && out.Headers == nil && out.BasicAuthSecretRef == nil
+	}
+	ref := (*CredentialsSecretRef)(in.CredentialsSecretRef.DeepCopy())
+	if a := in.Azure; a != nil {
+		out.Azure = &AzureBlob{AccountName: a.AccountName, Container: a.Container}
+		if in.Bucket == nil {
+			out.Azure.CredentialsSecretRef = ref
+		} else {
+			out.Azure.CredentialsSecretRef = restore.AzureCredentialsSecretRef.DeepCopy()
+		}
+	}
+	if b := in.Bucket; b != nil {
+		out.Bucket = &Bucket{
+			Name:                 b.Name,
+			CredentialsSecretRef: ref,
+			Region:               b.Region,
+			Endpoint:             b.Endpoint,
+			PathStyle:            b.PathStyle,
+			InsecureSkipVerify:   b.InsecureSkipVerify,
+		}
+	}
+	if in.Bucket == nil && in.Azure == nil {
+		data.CredentialsSecretRef = in.CredentialsSecretRef.DeepCopy()
+	}
+	return out
+}
+
+// source returns the recorded data of the i-th source, which is empty when nothing was recorded
+func (d *conversionData) source(i int) *sourceConversionData {
+	if i < len(d.Sources) {
+		return &d.Sources[i]
+	}
+	return &sourceConversionData{}
+}
+
+// pushConversionData records data in the annotation key of meta, unless there is nothing to record
+func pushConversionData(meta *metav1.ObjectMeta, key string, data conversionData) error {
+	empty := true
+	for _, s := range data.Sources {
+		empty = empty && s == sourceConversionData{}
+	}
+	if empty {
+		return nil
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[key] = string(b)
+	return nil
+}
+
+// popConversionData reads data from the annotation key of meta and removes the annotation
+func popConversionData(meta *metav1.ObjectMeta, key string, data *conversionData) error {
+	v, ok := meta.Annotations[key]
+	if !ok {
+		return nil
+	}
+	delete(meta.Annotations, key)
+	if len(meta.Annotations) == 0 {
+		meta.Annotations = nil
+	}
+	return json.Unmarshal([]byte(v), data)
+}
@@ -0,0 +1,128 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	fuzz "github.com/google/gofuzz"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/gojekfarm/darkroom-operator/pkg/api/v1beta1"
+)
+
+func newConversionFuzzer(seed int64) *fuzz.Fuzzer {
+	return fuzz.NewWithSeed(seed).NilChance(0.3).Funcs(
+		func(q *resource.Quantity, c fuzz.Continue) {
+			*q = *resource.NewQuantity(c.Int63n(1000), resource.DecimalSI)
+		},
+		func(t *v1.Time, c fuzz.Continue) {
+			*t = v1.Unix(c.Int63n(1<<32), 0)
+		},
+		func(t *v1.TypeMeta, _ fuzz.Continue) {
+			*t = v1.TypeMeta{}
+		},
+		// inline credentials are refused by the conversion, so they are left out of the round trips
+		func(b *Bucket, c fuzz.Continue) {
+			c.FuzzNoCustom(b)
+			b.AccessKey, b.SecretKey, b.CredentialsJson = "", "", ""
+		},
+		func(a *AzureBlob, c fuzz.Continue) {
+			c.FuzzNoCustom(a)
+			a.SASToken = ""
+		},
+	)
+}
+
+func TestDarkroom_ConvertRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 1000; seed++ {
+		f := newConversionFuzzer(seed)
+
+		in := &Darkroom{}
+		f.Fuzz(in)
+		hub := &v1beta1.Darkroom{}
+		assert.NoError(t, in.ConvertTo(hub))
+		out := &Darkroom{}
+		assert.NoError(t, out.ConvertFrom(hub))
+		if !assert.Equal(t, in, out, "v1alpha1 -> v1beta1 -> v1alpha1 with seed %d", seed) {
+			return
+		}
+	}
+}
+
+func TestDarkroom_ConvertToRefusesInlineCredentials(t *testing.T) {
+	for seed := int64(0); seed < 1000; seed++ {
+		f := newConversionFuzzer(seed)
+
+		in := &Darkroom{}
+		f.Fuzz(in)
+		in.Spec.FallbackSources = append(in.Spec.FallbackSources, Source{
+			Type:  AzureBlobStorage,
+			Azure: &AzureBlob{AccountName: "account", Container: "images", SASToken: "inline-sas-token"},
+		})
+		hub := &v1beta1.Darkroom{}
+		err := in.ConvertTo(hub)
+		if !assert.True(t, apierrors.IsInvalid(err), "v1alpha1 -> v1beta1 with seed %d converted inline credentials", seed) {
+			return
+		}
+		for key, value := range hub.Annotations {
+			if !assert.NotContains(t, value, "inline-sas-token", "v1alpha1 -> v1beta1 with seed %d leaks credentials into %s", seed, key) {
+				return
+			}
+		}
+	}
+
+	in := &Darkroom{Spec: DarkroomSpec{Source: Source{
+		Type:   S3,
+		Bucket: &Bucket{Name: "bucket", AccessKey: "access", SecretKey: "secret"},
+	}}}
+	assert.EqualError(t, in.ConvertTo(&v1beta1.Darkroom{}), `Darkroom.deployments.gojek.io "" is invalid: `+
+		`spec.source.bucket: Forbidden: inline credentials can not be converted to v1beta1, move them to credentialsSecretRef first`)
+}
+
+func TestDarkroom_ConvertHubRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 1000; seed++ {
+		f := newConversionFuzzer(seed)
+
+		in := &v1beta1.Darkroom{}
+		f.Fuzz(in)
+		spoke := &Darkroom{}
+		assert.NoError(t, spoke.ConvertFrom(in))
+		out := &v1beta1.Darkroom{}
+		assert.NoError(t, spoke.ConvertTo(out))
+		if !assert.Equal(t, in, out, "v1beta1 -> v1alpha1 -> v1beta1 with seed %d", seed) {
+			return
+		}
+	}
+}
+
+func TestDarkroom_ConvertTo(t *testing.T) {
+	in := &Darkroom{
+		ObjectMeta: v1.ObjectMeta{Name: "darkroom", Namespace: "default"},
+		Spec: DarkroomSpec{
+			Version: "0.1.0",
+			Source: Source{
+				Type:   S3,
+				Bucket: &Bucket{Name: "bucket", CredentialsSecretRef: &CredentialsSecretRef{Name: "credentials"}},
+			},
+			FallbackSources: []Source{{
+				Type:          WebFolder,
+				WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com/assets"},
+			}},
+		},
+	}
+	hub := &v1beta1.Darkroom{}
+	assert.NoError(t, in.ConvertTo(hub))
+
+	assert.Equal(t, v1beta1.Source{
+		Type:                 v1beta1.S3,
+		Bucket:               &v1beta1.Bucket{Name: "bucket"},
+		CredentialsSecretRef: &v1beta1.CredentialsSecretRef{Name: "credentials"},
+	}, hub.Spec.Source)
+	assert.Equal(t, []v1beta1.Source{{
+		Type:      v1beta1.WebFolder,
+		WebFolder: &v1beta1.WebFolderSource{BaseURL: "https://example.com/assets"},
+	}}, hub.Spec.FallbackSources)
+	assert.NotContains(t, hub.Annotations, v1alpha1ConversionDataAnnotation)
+}
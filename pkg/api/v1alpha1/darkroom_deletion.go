@@ -0,0 +1,34 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// CleanupFinalizer lets the controller tear a deleted Darkroom down in order before it is removed
+const CleanupFinalizer = "deployments.gojek.io/cleanup"
+
+// ProtectedAnnotation set to "true" blocks the deletion of a Darkroom
+const ProtectedAnnotation = "deployments.gojek.io/protected"
+
+// DeletionPolicy decides what happens to the objects generated for a Darkroom when it is deleted
+// +kubebuilder:validation:Enum=Delete;Orphan
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete removes the routes first, waits for the traffic to drain and then deletes the workloads
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyOrphan leaves the generated objects running, without the Darkroom as their owner
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+func (d *Darkroom) validateDeletionPolicy() *field.Error {
+	switch d.Spec.DeletionPolicy {
+	case "", DeletionPolicyDelete, DeletionPolicyOrphan:
+		return nil
+	}
+	return field.NotSupported(
+		field.NewPath("spec").Child("deletionPolicy"),
+		d.Spec.DeletionPolicy,
+		[]string{string(DeletionPolicyDelete), string(DeletionPolicyOrphan)},
+	)
+}
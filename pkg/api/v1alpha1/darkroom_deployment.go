@@ -0,0 +1,179 @@
+package v1alpha1
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// SelectorLabel is the pod label the Deployment and Service of a Darkroom select on
+const SelectorLabel = "darkroom"
+
+// Deployment configures the pods running darkroom
+type Deployment struct {
+	// Replicas is the number of darkroom pods, defaults to 1. It is ignored while spec.autoscaling is set.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Resources of the darkroom container
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// PodAnnotations are added to the darkroom pods
+	// +optional
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+	// PodLabels are added to the darkroom pods, the darkroom label is reserved
+	// +optional
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+	// Probes override the default HTTP probes of the darkroom container
+	// +optional
+	Probes *Probes `json:"probes,omitempty"`
+	// RollingUpdate configures the rolling update of the darkroom pods, defaults to a maxSurge of 25%
+	// and a maxUnavailable of 0
+	// +optional
+	RollingUpdate *appsv1.RollingUpdateDeployment `json:"rollingUpdate,omitempty"`
+}
+
+// Probes of the darkroom container, a probe without a handler probes the darkroom health endpoint
+type Probes struct {
+	// +optional
+	Liveness *corev1.Probe `json:"liveness,omitempty"`
+	// +optional
+	Readiness *corev1.Probe `json:"readiness,omitempty"`
+	// +optional
+	Startup *corev1.Probe `json:"startup,omitempty"`
+}
+
+func (d *Darkroom) validateDeployment() field.ErrorList {
+	dp := d.Spec.Deployment
+	if dp == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	path := field.NewPath("spec").Child("deployment")
+	if dp.Replicas != nil && *dp.Replicas < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("replicas"), *dp.Replicas, "must be greater than or equal to 0"))
+	}
+	for name, limit := range dp.Resources.Limits {
+		if request, ok := dp.Resources.Requests[name]; ok && request.Cmp(limit) > 0 {
+			allErrs = append(allErrs, field.Invalid(
+				path.Child("resources").Child("requests").Key(string(name)),
+				request.String(),
+				fmt.Sprintf("must be less than or equal to %s limit", name),
+			))
+		}
+	}
+	allErrs = append(allErrs, metav1validation.ValidateLabels(dp.NodeSelector, path.Child("nodeSelector"))...)
+	allErrs = append(allErrs, metav1validation.ValidateLabels(dp.PodLabels, path.Child("podLabels"))...)
+	if _, ok := dp.PodLabels[SelectorLabel]; ok {
+		allErrs = append(allErrs, field.Forbidden(
+			path.Child("podLabels").Key(SelectorLabel),
+			"label is reserved for the pod selector",
+		))
+	}
+	allErrs = append(allErrs, apivalidation.ValidateAnnotations(dp.PodAnnotations, path.Child("podAnnotations"))...)
+	for i, c := range dp.TopologySpreadConstraints {
+		if c.MaxSkew <= 0 {
+			allErrs = append(allErrs, field.Invalid(
+				path.Child("topologySpreadConstraints").Index(i).Child("maxSkew"),
+				c.MaxSkew,
+				"must be greater than 0",
+			))
+		}
+		if c.TopologyKey == "" {
+			allErrs = append(allErrs, field.Required(
+				path.Child("topologySpreadConstraints").Index(i).Child("topologyKey"),
+				"can not be empty",
+			))
+		}
+	}
+	allErrs = append(allErrs, validateRollingUpdate(path.Child("rollingUpdate"), dp.RollingUpdate)...)
+	if p := dp.Probes; p != nil {
+		path := path.Child("probes")
+		allErrs = append(allErrs, validateProbe(path.Child("liveness"), p.Liveness, true)...)
+		allErrs = append(allErrs, validateProbe(path.Child("readiness"), p.Readiness, false)...)
+		allErrs = append(allErrs, validateProbe(path.Child("startup"), p.Startup, true)...)
+	}
+	return allErrs
+}
+
+func validateRollingUpdate(path *field.Path, ru *appsv1.RollingUpdateDeployment) field.ErrorList {
+	if ru == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	surge, errs := validateIntOrPercent(path.Child("maxSurge"), ru.MaxSurge)
+	allErrs = append(allErrs, errs...)
+	unavailable, errs := validateIntOrPercent(path.Child("maxUnavailable"), ru.MaxUnavailable)
+	allErrs = append(allErrs, errs...)
+	if ru.MaxUnavailable != nil && ru.MaxUnavailable.Type == intstr.String && unavailable > 100 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxUnavailable"), ru.MaxUnavailable.String(), "must not be greater than 100%"))
+	}
+	if ru.MaxSurge != nil && ru.MaxUnavailable != nil && surge == 0 && unavailable == 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxUnavailable"), ru.MaxUnavailable.String(), "may not be 0 when maxSurge is 0"))
+	}
+	return allErrs
+}
+
+// validateIntOrPercent returns the value of a non-negative integer or percentage
+func validateIntOrPercent(path *field.Path, v *intstr.IntOrString) (int, field.ErrorList) {
+	if v == nil {
+		return 0, nil
+	}
+	value, err := intstr.GetScaledValueFromIntOrPercent(v, 100, true)
+	if err != nil {
+		return 0, field.ErrorList{field.Invalid(path, v.String(), "must be an integer or a percentage, e.g. 25%")}
+	}
+	if value < 0 {
+		return 0, field.ErrorList{field.Invalid(path, v.String(), "must be greater than or equal to 0")}
+	}
+	return value, nil
+}
+
+func validateProbe(path *field.Path, probe *corev1.Probe, singleSuccess bool) field.ErrorList {
+	if probe == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	handlers := 0
+	for _, set := range []bool{probe.Exec != nil, probe.HTTPGet != nil, probe.TCPSocket != nil} {
+		if set {
+			handlers++
+		}
+	}
+	if handlers > 1 {
+		allErrs = append(allErrs, field.Forbidden(path, "may not specify more than 1 handler type"))
+	}
+	for _, f := range []struct {
+		name  string
+		value int32
+	}{
+		{"initialDelaySeconds", probe.InitialDelaySeconds},
+		{"timeoutSeconds", probe.TimeoutSeconds},
+		{"periodSeconds", probe.PeriodSeconds},
+		{"successThreshold", probe.SuccessThreshold},
+		{"failureThreshold", probe.FailureThreshold},
+	} {
+		if f.value < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child(f.name), f.value, "must be greater than or equal to 0"))
+		}
+	}
+	if singleSuccess && probe.SuccessThreshold > 1 {
+		allErrs = append(allErrs, field.Invalid(path.Child("successThreshold"), probe.SuccessThreshold, "must be 1"))
+	}
+	return allErrs
+}
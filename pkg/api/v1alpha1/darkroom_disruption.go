@@ -0,0 +1,47 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// DisruptionBudget limits the voluntary disruptions of the darkroom pods, e.g. by node drains, with a
+// PodDisruptionBudget. It only applies while more than one replica is requested.
+type DisruptionBudget struct {
+	// Disabled removes the PodDisruptionBudget
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+	// MinAvailable is the number or percentage of darkroom pods that must stay available
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// MaxUnavailable is the number or percentage of darkroom pods that may be unavailable,
+	// defaults to 1 unless MinAvailable is set
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+func (d *Darkroom) validateDisruptionBudget() field.ErrorList {
+	b := d.Spec.DisruptionBudget
+	if b == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	path := field.NewPath("spec").Child("disruptionBudget")
+	if b.MinAvailable != nil && b.MaxUnavailable != nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("maxUnavailable"), "may not be set along with minAvailable"))
+	}
+	for _, f := range []struct {
+		name  string
+		value *intstr.IntOrString
+	}{
+		{"minAvailable", b.MinAvailable},
+		{"maxUnavailable", b.MaxUnavailable},
+	} {
+		value, errs := validateIntOrPercent(path.Child(f.name), f.value)
+		allErrs = append(allErrs, errs...)
+		if len(errs) == 0 && f.value != nil && f.value.Type == intstr.String && value > 100 {
+			allErrs = append(allErrs, field.Invalid(path.Child(f.name), f.value.String(), "must not be greater than 100%"))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,42 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// SourceStatus reports whether a source of the Darkroom validated successfully
+type SourceStatus struct {
+	// Path of the source in the spec, e.g. spec.source or spec.fallbackSources[0]
+	Path string `json:"path"`
+	// Type of the source
+	Type Type `json:"type"`
+	// Valid is true when the source and its credentials were validated successfully
+	Valid bool `json:"valid"`
+	// Message explains why the source is not valid
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// validateFallbackSources validates each of the fallback sources like the source. The circuit breaker of the
+// source guards all of them, and inline credentials are not supported.
+func (d *Darkroom) validateFallbackSources() field.ErrorList {
+	var allErrs field.ErrorList
+	for i := range d.Spec.FallbackSources {
+		s := &d.Spec.FallbackSources[i]
+		path := field.NewPath("spec").Child("fallbackSources").Index(i)
+		allErrs = append(allErrs, s.Validate(path)...)
+		if s.CircuitBreaker != nil {
+			allErrs = append(allErrs, field.Forbidden(
+				path.Child("circuitBreaker"),
+				"may not be set on a fallback source, spec.source.circuitBreaker guards all sources",
+			))
+		}
+		if s.HasInlineCredentials() {
+			allErrs = append(allErrs, field.Forbidden(
+				path,
+				"inline credentials are not supported on a fallback source, use credentialsSecretRef",
+			))
+		}
+	}
+	return allErrs
+}
@@ -11,24 +11,36 @@ const (
 	GoogleCloudStorage Type = "GoogleCloudStorage"
 )
 
-func (d *Darkroom) validateGoogleCloudStorage() *field.Error {
-	if d.Spec.Source.Bucket == nil {
+func (s *Source) validateGoogleCloudStorage(path *field.Path) *field.Error {
+	if err := s.validateNoWebFolderMeta(path); err != nil {
+		return err
+	}
+	if s.Bucket == nil {
 		return field.Required(
-			field.NewPath("spec").Child("source").Child("bucket"),
-			fmt.Sprintf("field required with Type %s", d.Spec.Source.Type),
+			path.Child("bucket"),
+			fmt.Sprintf("field required with Type %s", s.Type),
+		)
+	}
+	b := s.Bucket
+	if b.Region != "" || b.Endpoint != "" || b.PathStyle || b.InsecureSkipVerify {
+		return field.Forbidden(
+			path.Child("bucket"),
+			fmt.Sprintf("region, endpoint, pathStyle and insecureSkipVerify may not be set with Type %s", s.Type),
 		)
 	}
-	b := d.Spec.Source.Bucket
+	if b.CredentialsSecretRef != nil {
+		return validateCredentialsSecretRef(path.Child("bucket"), b.CredentialsSecretRef, b.HasInlineCredentials())
+	}
 	if b.CredentialsJson == "" {
 		return field.Required(
-			field.NewPath("spec").Child("source").Child("bucket").Child("credentialsJson"),
-			fmt.Sprintf("field required with Type %s", d.Spec.Source.Type),
+			path.Child("bucket").Child("credentialsJson"),
+			fmt.Sprintf("field required with Type %s", s.Type),
 		)
 	}
 	var js map[string]interface{}
 	if err := json.Unmarshal([]byte(b.CredentialsJson), &js); err != nil {
 		return field.Invalid(
-			field.NewPath("spec").Child("source").Child("bucket").Child("credentialsJson"),
+			path.Child("bucket").Child("credentialsJson"),
 			b.CredentialsJson,
 			err.Error(),
 		)
@@ -0,0 +1,94 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// LatestVersion is resolved into the default image tag of the operator by the defaulting webhook
+	LatestVersion = "latest"
+	// DefaultImageRepository is the repository of the darkroom image unless the operator is configured
+	// with another one, e.g. a mirror in an air-gapped cluster
+	DefaultImageRepository = "gojektech/darkroom"
+)
+
+var (
+	imageRepositoryRegexp = regexp.MustCompile(`^[a-z0-9]+([._/:-][a-z0-9]+)*$`)
+	imageTagRegexp        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	imageDigestRegexp     = regexp.MustCompile(`^[a-z0-9]+([+._-][a-z0-9]+)*:[0-9a-f]{32,}$`)
+)
+
+// Image overrides the darkroom container image
+type Image struct {
+	// Repository of the image, defaults to the repository configured on the operator
+	// +optional
+	Repository string `json:"repository,omitempty"`
+	// Tag of the image, takes precedence over Version
+	// +optional
+	Tag string `json:"tag,omitempty"`
+	// Digest pins the image, e.g. sha256:..., takes precedence over Tag and Version
+	// +optional
+	Digest string `json:"digest,omitempty"`
+	// PullPolicy of the darkroom container
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
+	// +optional
+	PullPolicy corev1.PullPolicy `json:"pullPolicy,omitempty"`
+	// ImagePullSecrets reference Secrets in the Darkroom namespace used to pull the image
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+// ResolveVersion replaces an empty or latest Version with tag, the default image tag of the operator
+func (d *Darkroom) ResolveVersion(tag string) {
+	if d.Spec.Version == "" || d.Spec.Version == LatestVersion {
+		d.Spec.Version = tag
+	}
+}
+
+// ImageReference returns the image of the darkroom container, falling back to repository and Version
+func (d *Darkroom) ImageReference(repository string) string {
+	tag := d.Spec.Version
+	if i := d.Spec.Image; i != nil {
+		if i.Repository != "" {
+			repository = i.Repository
+		}
+		if i.Digest != "" {
+			return fmt.Sprintf("%s@%s", repository, i.Digest)
+		}
+		if i.Tag != "" {
+			tag = i.Tag
+		}
+	}
+	return fmt.Sprintf("%s:%s", repository, tag)
+}
+
+func (d *Darkroom) validateImage() field.ErrorList {
+	i := d.Spec.Image
+	if i == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	path := field.NewPath("spec").Child("image")
+	if i.Repository != "" && !imageRepositoryRegexp.MatchString(i.Repository) {
+		allErrs = append(allErrs, field.Invalid(path.Child("repository"), i.Repository, "must be a valid image repository"))
+	}
+	if i.Tag != "" && !imageTagRegexp.MatchString(i.Tag) {
+		allErrs = append(allErrs, field.Invalid(path.Child("tag"), i.Tag, "must be a valid image tag"))
+	}
+	if i.Digest != "" && !imageDigestRegexp.MatchString(i.Digest) {
+		allErrs = append(allErrs, field.Invalid(path.Child("digest"), i.Digest, "must be a valid image digest, e.g. sha256:<hex>"))
+	}
+	if i.Tag != "" && i.Digest != "" {
+		allErrs = append(allErrs, field.Forbidden(path.Child("digest"), "may not be set along with tag"))
+	}
+	for j, s := range i.ImagePullSecrets {
+		if s.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("imagePullSecrets").Index(j).Child("name"), "name of the Secret is required"))
+		}
+	}
+	return allErrs
+}
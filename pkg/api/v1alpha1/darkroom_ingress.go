@@ -0,0 +1,28 @@
+package v1alpha1
+
+// Ingress configures how the Domains of a Darkroom are routed to its Service
+type Ingress struct {
+	// ClassName of the IngressClass handling the generated Ingress
+	// +optional
+	ClassName *string `json:"className,omitempty"`
+	// Annotations added to the generated Ingress or HTTPRoute
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// TLSSecretName is the Secret holding the certificate served for the Domains
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+	// GatewayRef routes the Domains through a Gateway API HTTPRoute attached to the referenced Gateway
+	// instead of an Ingress, when the Gateway API CRDs are installed. Without them an Ingress is rendered
+	// and a GatewayAPIUnavailable warning Event is recorded.
+	// +optional
+	GatewayRef *GatewayRef `json:"gatewayRef,omitempty"`
+}
+
+// GatewayRef references a Gateway API Gateway
+type GatewayRef struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Namespace of the Gateway, defaults to the Darkroom namespace
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
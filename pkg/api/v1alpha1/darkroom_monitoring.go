@@ -0,0 +1,34 @@
+package v1alpha1
+
+import (
+	"regexp"
+
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var durationRegexp = regexp.MustCompile(`^(0|(([0-9]+)h)?(([0-9]+)m)?(([0-9]+)s)?(([0-9]+)ms)?)$`)
+
+// ServiceMonitor makes the Prometheus Operator scrape the metrics port of darkroom
+type ServiceMonitor struct {
+	// Labels added to the ServiceMonitor, e.g. to match the serviceMonitorSelector of a Prometheus
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Interval between scrapes, e.g. 30s, defaults to the interval of the Prometheus
+	// +kubebuilder:validation:Pattern=`^(0|(([0-9]+)h)?(([0-9]+)m)?(([0-9]+)s)?(([0-9]+)ms)?)$`
+	// +optional
+	Interval string `json:"interval,omitempty"`
+}
+
+func (d *Darkroom) validateServiceMonitor() field.ErrorList {
+	sm := d.Spec.ServiceMonitor
+	if sm == nil {
+		return nil
+	}
+	path := field.NewPath("spec").Child("serviceMonitor")
+	allErrs := metav1validation.ValidateLabels(sm.Labels, path.Child("labels"))
+	if !durationRegexp.MatchString(sm.Interval) {
+		allErrs = append(allErrs, field.Invalid(path.Child("interval"), sm.Interval, "must be a duration, e.g. 30s"))
+	}
+	return allErrs
+}
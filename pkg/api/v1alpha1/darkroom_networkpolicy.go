@@ -0,0 +1,77 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"net"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// NetworkPolicy restricts the traffic of the darkroom pods to the ingress controller, Prometheus, DNS and the
+// source origins
+type NetworkPolicy struct {
+	// IngressNamespaceSelector selects the namespaces of the ingress controller allowed to reach darkroom
+	// +optional
+	IngressNamespaceSelector *metav1.LabelSelector `json:"ingressNamespaceSelector,omitempty"`
+	// IngressPodSelector selects the ingress controller pods allowed to reach darkroom
+	// +optional
+	IngressPodSelector *metav1.LabelSelector `json:"ingressPodSelector,omitempty"`
+	// EgressCIDRs are the addresses of the source origins, required for S3, GoogleCloudStorage and
+	// AzureBlobStorage sources. A WebFolder origin given by IP address is allowed without them, one given
+	// by host name is reachable at these addresses or, without them, at any address on its port.
+	// +optional
+	EgressCIDRs []string `json:"egressCIDRs,omitempty"`
+	// PrometheusNamespaceSelector selects the namespaces of the Prometheus allowed to scrape the metrics port
+	// of darkroom, it or PrometheusPodSelector is required when a ServiceMonitor is requested
+	// +optional
+	PrometheusNamespaceSelector *metav1.LabelSelector `json:"prometheusNamespaceSelector,omitempty"`
+	// PrometheusPodSelector selects the Prometheus pods allowed to scrape the metrics port of darkroom
+	// +optional
+	PrometheusPodSelector *metav1.LabelSelector `json:"prometheusPodSelector,omitempty"`
+}
+
+func (d *Darkroom) validateNetworkPolicy() field.ErrorList {
+	np := d.Spec.NetworkPolicy
+	if np == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	path := field.NewPath("spec").Child("networkPolicy")
+	if np.IngressNamespaceSelector == nil && np.IngressPodSelector == nil {
+		allErrs = append(allErrs, field.Required(path.Child("ingressNamespaceSelector"),
+			"ingressNamespaceSelector or ingressPodSelector is required"))
+	}
+	if np.IngressNamespaceSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(np.IngressNamespaceSelector, path.Child("ingressNamespaceSelector"))...)
+	}
+	if np.IngressPodSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(np.IngressPodSelector, path.Child("ingressPodSelector"))...)
+	}
+	if d.Spec.ServiceMonitor != nil && np.PrometheusNamespaceSelector == nil && np.PrometheusPodSelector == nil {
+		allErrs = append(allErrs, field.Required(path.Child("prometheusNamespaceSelector"),
+			"prometheusNamespaceSelector or prometheusPodSelector is required with a serviceMonitor"))
+	}
+	if np.PrometheusNamespaceSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(np.PrometheusNamespaceSelector, path.Child("prometheusNamespaceSelector"))...)
+	}
+	if np.PrometheusPodSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(np.PrometheusPodSelector, path.Child("prometheusPodSelector"))...)
+	}
+	for i, cidr := range np.EgressCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("egressCIDRs").Index(i), cidr, "must be a valid CIDR, e.g. 10.0.0.0/16"))
+		}
+	}
+	if len(np.EgressCIDRs) == 0 {
+		for _, s := range append([]Source{d.Spec.Source}, d.Spec.FallbackSources...) {
+			if s.Type != WebFolder {
+				allErrs = append(allErrs, field.Required(path.Child("egressCIDRs"),
+					fmt.Sprintf("field required with sources of Type %s", s.Type)))
+				break
+			}
+		}
+	}
+	return allErrs
+}
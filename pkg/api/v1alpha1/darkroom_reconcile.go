@@ -0,0 +1,35 @@
+package v1alpha1
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// PausedAnnotation set to "true" stops the controller from applying the resources of a Darkroom,
+// e.g. while its Deployment is patched by hand
+const PausedAnnotation = "deployments.gojek.io/paused"
+
+// ReconcileAtAnnotation requests a full reconcile of a Darkroom every time it is set to a new RFC 3339 timestamp,
+// the last handled one is reported in status.lastHandledReconcileAt
+const ReconcileAtAnnotation = "deployments.gojek.io/reconcile-at"
+
+// Paused reports whether the reconciliation of d is paused with PausedAnnotation
+func (d *Darkroom) Paused() bool {
+	return d.Annotations[PausedAnnotation] == "true"
+}
+
+func (d *Darkroom) validateReconcileAt() *field.Error {
+	v, ok := d.Annotations[ReconcileAtAnnotation]
+	if !ok {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, v); err != nil {
+		return field.Invalid(
+			field.NewPath("metadata").Child("annotations").Key(ReconcileAtAnnotation),
+			v,
+			"must be an RFC 3339 timestamp",
+		)
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package v1alpha1
 
 import (
 	"fmt"
+	"net/url"
+	"regexp"
 
 	"k8s.io/apimachinery/pkg/util/validation/field"
 )
@@ -10,25 +12,62 @@ const (
 	S3 Type = "S3"
 )
 
-func (d *Darkroom) validateS3() *field.Error {
-	if d.Spec.Source.Bucket == nil {
+var s3RegionRegexp = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+func (s *Source) validateS3(path *field.Path) *field.Error {
+	if err := s.validateNoWebFolderMeta(path); err != nil {
+		return err
+	}
+	if s.Bucket == nil {
 		return field.Required(
-			field.NewPath("spec").Child("source").Child("bucket"),
-			fmt.Sprintf("field required with Type %s", d.Spec.Source.Type),
+			path.Child("bucket"),
+			fmt.Sprintf("field required with Type %s", s.Type),
 		)
 	}
-	b := d.Spec.Source.Bucket
+	b := s.Bucket
+	if err := validateS3Endpoint(path.Child("bucket"), b); err != nil {
+		return err
+	}
+	if b.CredentialsSecretRef != nil {
+		return validateCredentialsSecretRef(path.Child("bucket"), b.CredentialsSecretRef, b.HasInlineCredentials())
+	}
 	if b.AccessKey == "" {
 		return field.Required(
-			field.NewPath("spec").Child("source").Child("bucket").Child("accessKey"),
-			fmt.Sprintf("field required with Type %s", d.Spec.Source.Type),
+			path.Child("bucket").Child("accessKey"),
+			fmt.Sprintf("field required with Type %s", s.Type),
 		)
 	}
 	if b.SecretKey == "" {
 		return field.Required(
-			field.NewPath("spec").Child("source").Child("bucket").Child("secretKey"),
-			fmt.Sprintf("field required with Type %s", d.Spec.Source.Type),
+			path.Child("bucket").Child("secretKey"),
+			fmt.Sprintf("field required with Type %s", s.Type),
 		)
 	}
 	return nil
 }
+
+func validateS3Endpoint(path *field.Path, b *Bucket) *field.Error {
+	if b.Region != "" && !s3RegionRegexp.MatchString(b.Region) {
+		return field.Invalid(path.Child("region"), b.Region, "must consist of lower case alphanumeric characters or '-'")
+	}
+	if b.Endpoint == "" {
+		if b.InsecureSkipVerify {
+			return field.Forbidden(path.Child("insecureSkipVerify"), "may only be set along with endpoint")
+		}
+		return nil
+	}
+	u, err := url.ParseRequestURI(b.Endpoint)
+	if err != nil {
+		return field.Invalid(path.Child("endpoint"), b.Endpoint, err.Error())
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return field.Invalid(path.Child("endpoint"), b.Endpoint, "scheme must be http or https")
+	}
+	if u.Host == "" {
+		return field.Invalid(path.Child("endpoint"), b.Endpoint, "host is required")
+	}
+	if b.InsecureSkipVerify && u.Scheme != "https" {
+		return field.Forbidden(path.Child("insecureSkipVerify"), "may only be set along with an https endpoint")
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package v1alpha1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	DefaultLogLevel    = "info"
+	DefaultPort        = int32(3000)
+	DefaultMetricsPort = int32(9090)
+	DefaultCacheTime   = int64(31536000)
+
+	DefaultCircuitBreakerTimeout                = int32(5000)
+	DefaultCircuitBreakerMaxConcurrentRequests  = int32(100)
+	DefaultCircuitBreakerRequestVolumeThreshold = int32(10)
+	DefaultCircuitBreakerSleepWindow            = int32(10)
+	DefaultCircuitBreakerErrorPercentThreshold  = int32(25)
+)
+
+// Server tunes the darkroom server
+type Server struct {
+	// Debug enables the debug mode of darkroom
+	// +optional
+	Debug bool `json:"debug,omitempty"`
+	// +kubebuilder:validation:Enum=debug;info;warn;error
+	// +kubebuilder:default=info
+	// +optional
+	LogLevel string `json:"logLevel,omitempty"`
+	// Port the darkroom container listens on
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=3000
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// MetricsPort the darkroom container serves its Prometheus metrics on
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=9090
+	// +optional
+	MetricsPort int32 `json:"metricsPort,omitempty"`
+	// CacheTime is the max-age, in seconds, of the Cache-Control header of the served images
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	CacheTime *int64 `json:"cacheTime,omitempty"`
+}
+
+// CircuitBreaker tunes the hystrix circuit breaker guarding the requests to the source
+type CircuitBreaker struct {
+	// Timeout of a request to the source, in milliseconds
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=60000
+	// +kubebuilder:default=5000
+	// +optional
+	Timeout int32 `json:"timeout,omitempty"`
+	// MaxConcurrentRequests to the source
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10000
+	// +kubebuilder:default=100
+	// +optional
+	MaxConcurrentRequests int32 `json:"maxConcurrentRequests,omitempty"`
+	// RequestVolumeThreshold is the minimum number of requests in a window before the circuit can trip
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10000
+	// +kubebuilder:default=10
+	// +optional
+	RequestVolumeThreshold int32 `json:"requestVolumeThreshold,omitempty"`
+	// SleepWindow is the time after tripping the circuit before trying the source again, in milliseconds
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=300000
+	// +kubebuilder:default=10
+	// +optional
+	SleepWindow int32 `json:"sleepWindow,omitempty"`
+	// ErrorPercentThreshold is the percentage of failed requests tripping the circuit
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=25
+	// +optional
+	ErrorPercentThreshold int32 `json:"errorPercentThreshold,omitempty"`
+}
+
+func (s *Server) Default() {
+	if s.LogLevel == "" {
+		s.LogLevel = DefaultLogLevel
+	}
+	if s.Port == 0 {
+		s.Port = DefaultPort
+	}
+	if s.MetricsPort == 0 {
+		s.MetricsPort = DefaultMetricsPort
+	}
+	if s.CacheTime == nil {
+		cacheTime := DefaultCacheTime
+		s.CacheTime = &cacheTime
+	}
+}
+
+func (c *CircuitBreaker) Default() {
+	if c.Timeout == 0 {
+		c.Timeout = DefaultCircuitBreakerTimeout
+	}
+	if c.MaxConcurrentRequests == 0 {
+		c.MaxConcurrentRequests = DefaultCircuitBreakerMaxConcurrentRequests
+	}
+	if c.RequestVolumeThreshold == 0 {
+		c.RequestVolumeThreshold = DefaultCircuitBreakerRequestVolumeThreshold
+	}
+	if c.SleepWindow == 0 {
+		c.SleepWindow = DefaultCircuitBreakerSleepWindow
+	}
+	if c.ErrorPercentThreshold == 0 {
+		c.ErrorPercentThreshold = DefaultCircuitBreakerErrorPercentThreshold
+	}
+}
+
+// validateServer validates the tuning of darkroom, unset values are left to Default
+func (d *Darkroom) validateServer() field.ErrorList {
+	var allErrs field.ErrorList
+	s, c := Server{}, CircuitBreaker{}
+	if d.Spec.Server != nil {
+		s = *d.Spec.Server
+	}
+	if d.Spec.Source.CircuitBreaker != nil {
+		c = *d.Spec.Source.CircuitBreaker
+	}
+	path := field.NewPath("spec").Child("server")
+	switch s.LogLevel {
+	case "", "debug", "info", "warn", "error":
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("logLevel"), s.LogLevel, []string{"debug", "info", "warn", "error"}))
+	}
+	allErrs = append(allErrs, validateRange(path.Child("port"), int64(s.Port), 0, 65535)...)
+	allErrs = append(allErrs, validateRange(path.Child("metricsPort"), int64(s.MetricsPort), 0, 65535)...)
+	defaulted := s
+	defaulted.Default()
+	if defaulted.MetricsPort == defaulted.Port {
+		allErrs = append(allErrs, field.Invalid(path.Child("metricsPort"), defaulted.MetricsPort, "must differ from port"))
+	}
+	if s.CacheTime != nil && *s.CacheTime < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("cacheTime"), *s.CacheTime, "must be greater than or equal to 0"))
+	}
+
+	path = field.NewPath("spec").Child("source").Child("circuitBreaker")
+	allErrs = append(allErrs, validateRange(path.Child("timeout"), int64(c.Timeout), 0, 60000)...)
+	allErrs = append(allErrs, validateRange(path.Child("maxConcurrentRequests"), int64(c.MaxConcurrentRequests), 0, 10000)...)
+	allErrs = append(allErrs, validateRange(path.Child("requestVolumeThreshold"), int64(c.RequestVolumeThreshold), 0, 10000)...)
+	allErrs = append(allErrs, validateRange(path.Child("sleepWindow"), int64(c.SleepWindow), 0, 300000)...)
+	allErrs = append(allErrs, validateRange(path.Child("errorPercentThreshold"), int64(c.ErrorPercentThreshold), 0, 100)...)
+
+	for name := range d.Spec.ExtraEnv {
+		for _, msg := range validation.IsEnvVarName(name) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec").Child("extraEnv").Key(name), name, msg))
+		}
+	}
+	return allErrs
+}
+
+func validateRange(path *field.Path, value, min, max int64) field.ErrorList {
+	if value < min || value > max {
+		return field.ErrorList{field.Invalid(path, value, fmt.Sprintf("must be between %d and %d", min, max))}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package v1alpha1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// TLS requests a cert-manager Certificate covering the Domains of a Darkroom
+type TLS struct {
+	IssuerRef IssuerRef `json:"issuerRef"`
+	// SecretName of the Secret the certificate is stored in, defaults to <name>-tls
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// IssuerRef references the cert-manager issuer signing the certificate
+type IssuerRef struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +kubebuilder:default=Issuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// +kubebuilder:default=cert-manager.io
+	// +optional
+	Group string `json:"group,omitempty"`
+}
+
+func (t *TLS) Default(name string) {
+	if t.SecretName == "" {
+		t.SecretName = fmt.Sprintf("%s-tls", name)
+	}
+	if t.IssuerRef.Kind == "" {
+		t.IssuerRef.Kind = "Issuer"
+	}
+	if t.IssuerRef.Group == "" {
+		t.IssuerRef.Group = "cert-manager.io"
+	}
+}
+
+func (d *Darkroom) validateTLS() *field.Error {
+	if d.Spec.TLS != nil && d.Spec.Ingress != nil && d.Spec.Ingress.TLSSecretName != "" {
+		return field.Forbidden(
+			field.NewPath("spec").Child("ingress").Child("tlsSecretName"),
+			"may not be set along with spec.tls",
+		)
+	}
+	return nil
+}
@@ -29,13 +29,33 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
-// +kubebuilder:validation:Enum=WebFolder;S3;GoogleCloudStorage
+// +kubebuilder:validation:Enum=WebFolder;S3;GoogleCloudStorage;AzureBlobStorage
 type Type string
 
 type DeployState string
 
 const (
 	Deploying DeployState = "Deploying"
+	Deployed  DeployState = "Deployed"
+	Failed    DeployState = "Failed"
+	Deleting  DeployState = "Deleting"
+)
+
+const (
+	// ReadyCondition is True when the desired number of darkroom replicas are available
+	ReadyCondition = "Ready"
+	// ProgressingCondition is True while the darkroom Deployment is rolling out
+	ProgressingCondition = "Progressing"
+	// DegradedCondition is True when the darkroom Deployment failed to roll out
+	DegradedCondition = "Degraded"
+	// SourceReachableCondition is False when the darkroom source can not be resolved, e.g. missing credentials
+	SourceReachableCondition = "SourceReachable"
+	// ReconciledCondition is False when the controller failed to apply the resources of the darkroom
+	ReconciledCondition = "Reconciled"
+	// CertificateReadyCondition is True when the certificate requested through spec.tls has been issued
+	CertificateReadyCondition = "CertificateReady"
+	// PausedCondition is True while the controller leaves the resources of the darkroom as they are
+	PausedCondition = "Paused"
 )
 
 type Source struct {
@@ -44,41 +64,133 @@ type Source struct {
 	// - "WebFolder": simple storage backend to serve images from a hosted image source;
 	// - "S3": storage backend to serve images from S3 backend;
 	// - "GoogleCloudStorage": storage backend to serve images from GoogleCloudStorage backend;
+	// - "AzureBlobStorage": storage backend to serve images from an Azure Blob Storage container;
 	Type Type `json:"type"`
 
 	WebFolderMeta `json:",inline"`
 
 	Bucket *Bucket `json:"bucket,omitempty"`
 
+	// Azure locates the container of the AzureBlobStorage source
+	// +optional
+	Azure *AzureBlob `json:"azure,omitempty"`
+
 	// +kubebuilder:default="/"
 	// +optional
 	Prefix string `json:"prefix,omitempty"`
+
+	// CircuitBreaker tunes the circuit breaker guarding the requests to the source
+	// +optional
+	CircuitBreaker *CircuitBreaker `json:"circuitBreaker,omitempty"`
 }
 
 // DarkroomSpec defines the desired state of Darkroom
 type DarkroomSpec struct {
+	// Version is the tag of the darkroom image, an empty or latest Version is resolved into the default tag
+	// configured on the operator
 	// +optional
 	Version string `json:"version"`
 
+	// Image overrides the repository, tag or digest and pull settings of the darkroom image
+	// +optional
+	Image *Image `json:"image,omitempty"`
+
 	Source Source `json:"source"`
 
+	// FallbackSources are consulted in order when an image is missing from Source, e.g. while migrating
+	// images between origins. Credentials of fallback sources must be given through credentialsSecretRef.
+	// +optional
+	FallbackSources []Source `json:"fallbackSources,omitempty"`
+
 	// +optional
 	// PathPrefix prepends the prefix in the URL when serving images
 	PathPrefix string `json:"pathPrefix,omitempty"`
 
 	// +kubebuilder:validation:MinItems=1
 	Domains []string `json:"domains"`
+
+	// Server tunes the darkroom server
+	// +optional
+	Server *Server `json:"server,omitempty"`
+	// ExtraEnv sets darkroom settings not covered by the spec, settings managed by the operator take precedence
+	// +optional
+	ExtraEnv map[string]string `json:"extraEnv,omitempty"`
+	// Deployment configures the replicas, resources and scheduling of the darkroom pods
+	// +optional
+	Deployment *Deployment `json:"deployment,omitempty"`
+	// Autoscaling scales the darkroom pods with a HorizontalPodAutoscaler
+	// +optional
+	Autoscaling *Autoscaling `json:"autoscaling,omitempty"`
+	// DisruptionBudget limits the voluntary disruptions of the darkroom pods
+	// +optional
+	DisruptionBudget *DisruptionBudget `json:"disruptionBudget,omitempty"`
+	// TLS issues a certificate for the Domains through cert-manager and serves it on the generated Ingress
+	// +optional
+	TLS *TLS `json:"tls,omitempty"`
+	// Ingress configures the routing of Domains to darkroom
+	// +optional
+	Ingress *Ingress `json:"ingress,omitempty"`
+	// NetworkPolicy restricts the traffic of the darkroom pods, no NetworkPolicy is generated unless it is set
+	// +optional
+	NetworkPolicy *NetworkPolicy `json:"networkPolicy,omitempty"`
+	// ServiceMonitor makes the Prometheus Operator scrape darkroom, when it is installed
+	// +optional
+	ServiceMonitor *ServiceMonitor `json:"serviceMonitor,omitempty"`
+	// DeletionPolicy decides whether the generated objects are deleted or orphaned along with the Darkroom
+	// +kubebuilder:default=Delete
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
 }
 
 // DarkroomStatus defines the observed state of Darkroom
 type DarkroomStatus struct {
 	DeployState DeployState `json:"deployState"`
+	// Domains lists the hosts admitted by the ingress controller or Gateway
 	// +optional
 	Domains []string `json:"domains,omitempty"`
+	// ObservedGeneration is the most recent generation observed by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Replicas is the number of darkroom pods targeted by the Deployment
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+	// UpdatedReplicas is the number of darkroom pods running the desired template
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+	// ReadyReplicas is the number of ready darkroom pods
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// AvailableReplicas is the number of available darkroom pods
+	// +optional
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+	// ConfigHash is the hash of the configuration and credentials the darkroom pods are rolled out with
+	// +optional
+	ConfigHash string `json:"configHash,omitempty"`
+	// Selector is the label selector of the darkroom pods, used by the scale subresource
+	// +optional
+	Selector string `json:"selector,omitempty"`
+	// Sources reports whether the source and each of the fallback sources validated successfully
+	// +optional
+	Sources []SourceStatus `json:"sources,omitempty"`
+	// LastHandledReconcileAt is the last value of the reconcile-at annotation the controller reconciled
+	// +optional
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
+	// Conditions represent the latest observations of the darkroom state
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:scale:specpath=.spec.deployment.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.deployState`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Darkroom is the Schema for the darkrooms API
 type Darkroom struct {
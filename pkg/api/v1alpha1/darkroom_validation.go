@@ -1,6 +1,12 @@
 package v1alpha1
 
 import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -10,28 +16,211 @@ import (
 func (d *Darkroom) Default() {
 	log.Info("default", "name", d.Name)
 
-	if d.Spec.Version == "" {
-		d.Spec.Version = "latest"
+	d.ResolveVersion(LatestVersion)
+	d.Spec.Source.defaultSecretRefs()
+	for i := range d.Spec.FallbackSources {
+		d.Spec.FallbackSources[i].defaultSecretRefs()
+	}
+	if d.Spec.TLS != nil {
+		d.Spec.TLS.Default(d.Name)
+	}
+	if d.Spec.Server == nil {
+		d.Spec.Server = &Server{}
+	}
+	d.Spec.Server.Default()
+	if d.Spec.Source.CircuitBreaker == nil {
+		d.Spec.Source.CircuitBreaker = &CircuitBreaker{}
+	}
+	d.Spec.Source.CircuitBreaker.Default()
+}
+
+func (s *Source) defaultSecretRefs() {
+	if ref := s.CredentialsSecretRef(); ref != nil {
+		ref.Default()
+	}
+	if ref := s.BasicAuthSecretRef; ref != nil {
+		ref.Default()
 	}
 }
 
+// MigrateSourceAnnotation allows changing the source type and bucket of a Darkroom, which are immutable otherwise
+const MigrateSourceAnnotation = "deployments.gojek.io/migrate-source"
+
 func (d *Darkroom) ValidateCreate() error {
 	log.Info("validate create", "name", d.Name)
+	return d.invalid(d.validate())
+}
+
+func (d *Darkroom) ValidateUpdate(old runtime.Object) error {
+	log.Info("validate update", "name", d.Name)
+	oldDarkroom, ok := old.(*Darkroom)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a Darkroom but got a %T", old))
+	}
+	// a Darkroom being deleted may still need to be updated, e.g. to remove its finalizers, but its spec is
+	// left as it is while the controller drains it
+	if !d.DeletionTimestamp.IsZero() {
+		if apiequality.Semantic.DeepEqual(d.Spec, oldDarkroom.Spec) {
+			return nil
+		}
+		return d.invalid(field.ErrorList{
+			field.Forbidden(field.NewPath("spec"), "may not be changed while the Darkroom is being deleted"),
+		})
+	}
+	allErrs := ratchet(d, oldDarkroom, d.validate(), oldDarkroom.validate())
+	allErrs = append(allErrs, d.validateTransition(oldDarkroom)...)
+	return d.invalid(allErrs)
+}
+
+// ratchet drops the errors of allErrs that the previous version of the Darkroom already had, so that Darkrooms
+// admitted before a rule was added can still be updated as long as the update leaves the offending fields as they are.
+// An error is only dropped when old had one of the same type at the same field and the value of that field is
+// unchanged. Required and Forbidden errors carry no value and are about the fields next to theirs, so for them the
+// value of the parent field has to be unchanged.
+func ratchet(d, old *Darkroom, allErrs, oldErrs field.ErrorList) field.ErrorList {
+	if len(allErrs) == 0 || len(oldErrs) == 0 {
+		return allErrs
+	}
+	current, err := runtime.DefaultUnstructuredConverter.ToUnstructured(d)
+	if err != nil {
+		return allErrs
+	}
+	previous, err := runtime.DefaultUnstructuredConverter.ToUnstructured(old)
+	if err != nil {
+		return allErrs
+	}
+	existing := make(map[string]bool, len(oldErrs))
+	for _, err := range oldErrs {
+		existing[string(err.Type)+" "+err.Field] = true
+	}
+	var errs field.ErrorList
+	for _, err := range allErrs {
+		path := fieldPathSegments(err.Field)
+		if (err.Type == field.ErrorTypeRequired || err.Type == field.ErrorTypeForbidden) && len(path) > 0 {
+			path = path[:len(path)-1]
+		}
+		if !existing[string(err.Type)+" "+err.Field] || !reflect.DeepEqual(valueAt(current, path), valueAt(previous, path)) {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// fieldPathSegments splits a field path, e.g. spec.fallbackSources[0].bucket, into its field names, indices and keys
+func fieldPathSegments(p string) []string {
+	var segments []string
+	for p != "" {
+		end := strings.IndexAny(p, ".[")
+		if p[0] == '[' {
+			end = strings.IndexByte(p, ']')
+			if end < 0 {
+				return append(segments, p[1:])
+			}
+			segments, p = append(segments, p[1:end]), p[end+1:]
+		} else if end < 0 {
+			segments, p = append(segments, p), ""
+		} else {
+			segments, p = append(segments, p[:end]), p[end:]
+		}
+		p = strings.TrimPrefix(p, ".")
+	}
+	return segments
+}
+
+// valueAt returns the value at path of the unstructured obj, or nil when it has none
+func valueAt(obj interface{}, path []string) interface{} {
+	for _, segment := range path {
+		switch o := obj.(type) {
+		case map[string]interface{}:
+			obj = o[segment]
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(o) {
+				return nil
+			}
+			obj = o[i]
+		default:
+			return nil
+		}
+	}
+	return obj
+}
+
+func (d *Darkroom) validate() field.ErrorList {
+	allErrs := d.Spec.Source.Validate(field.NewPath("spec").Child("source"))
+	allErrs = append(allErrs, d.validateFallbackSources()...)
+	allErrs = append(allErrs, d.validateImage()...)
+	allErrs = append(allErrs, d.validateServer()...)
+	allErrs = append(allErrs, d.validateDeployment()...)
+	allErrs = append(allErrs, d.validateAutoscaling()...)
+	allErrs = append(allErrs, d.validateDisruptionBudget()...)
+	allErrs = append(allErrs, d.validateNetworkPolicy()...)
+	allErrs = append(allErrs, d.validateServiceMonitor()...)
+	if err := d.validateDeletionPolicy(); err != nil {
+		allErrs = append(allErrs, err)
+	}
+	if err := d.validateReconcileAt(); err != nil {
+		allErrs = append(allErrs, err)
+	}
+	if err := d.validateTLS(); err != nil {
+		allErrs = append(allErrs, err)
+	}
+	return allErrs
+}
+
+// Validate validates the fields of the source against its Type, reporting them under path
+func (s *Source) Validate(path *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
-	switch d.Spec.Source.Type {
+	if s.Type != AzureBlobStorage && s.Azure != nil {
+		allErrs = append(allErrs, field.Forbidden(
+			path.Child("azure"),
+			fmt.Sprintf("may not be set with Type %s", s.Type),
+		))
+	}
+	switch s.Type {
 	case WebFolder:
-		if err := d.validateWebFolder(); err != nil {
-			allErrs = append(allErrs, err)
-		}
+		allErrs = append(allErrs, s.validateWebFolder(path)...)
 	case S3:
-		if err := d.validateS3(); err != nil {
+		if err := s.validateS3(path); err != nil {
 			allErrs = append(allErrs, err)
 		}
 	case GoogleCloudStorage:
-		if err := d.validateGoogleCloudStorage(); err != nil {
+		if err := s.validateGoogleCloudStorage(path); err != nil {
 			allErrs = append(allErrs, err)
 		}
+	case AzureBlobStorage:
+		allErrs = append(allErrs, s.validateAzureBlobStorage(path)...)
+	}
+	return allErrs
+}
+
+// validateTransition validates the changes from old, fields of the source are immutable
+// unless the migration is requested with MigrateSourceAnnotation
+func (d *Darkroom) validateTransition(old *Darkroom) field.ErrorList {
+	if d.Annotations[MigrateSourceAnnotation] == "true" {
+		return nil
+	}
+	var allErrs field.ErrorList
+	path := field.NewPath("spec").Child("source")
+	msg := fmt.Sprintf("field is immutable unless the %s annotation is set to \"true\"", MigrateSourceAnnotation)
+	if d.Spec.Source.Type != old.Spec.Source.Type {
+		allErrs = append(allErrs, field.Forbidden(path.Child("type"), msg))
+	}
+	if b, ob := d.Spec.Source.Bucket, old.Spec.Source.Bucket; b != nil && ob != nil && b.Name != ob.Name {
+		allErrs = append(allErrs, field.Forbidden(path.Child("bucket").Child("name"), msg))
+	}
+	if a, oa := d.Spec.Source.Azure, old.Spec.Source.Azure; a != nil && oa != nil {
+		if a.AccountName != oa.AccountName {
+			allErrs = append(allErrs, field.Forbidden(path.Child("azure").Child("accountName"), msg))
+		}
+		if a.Container != oa.Container {
+			allErrs = append(allErrs, field.Forbidden(path.Child("azure").Child("container"), msg))
+		}
 	}
+	return allErrs
+}
+
+func (d *Darkroom) invalid(allErrs field.ErrorList) error {
 	if len(allErrs) == 0 {
 		return nil
 	}
@@ -40,12 +229,14 @@ func (d *Darkroom) ValidateCreate() error {
 		d.Name, allErrs)
 }
 
-func (d *Darkroom) ValidateUpdate(old runtime.Object) error {
-	log.Info("validate update", "name", d.Name)
-	return nil
-}
-
 func (d *Darkroom) ValidateDelete() error {
 	log.Info("validate delete", "name", d.Name)
+	if d.Annotations[ProtectedAnnotation] == "true" {
+		return apierrors.NewForbidden(
+			schema.GroupResource{Group: GroupVersion.Group, Resource: "darkrooms"},
+			d.Name,
+			fmt.Errorf("the Darkroom is protected, remove the %s annotation to delete it", ProtectedAnnotation),
+		)
+	}
 	return nil
 }
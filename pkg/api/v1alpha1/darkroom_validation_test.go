@@ -1,17 +1,32 @@
 package v1alpha1
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func TestDarkroom_Default(t *testing.T) {
 	type fields struct {
 		Spec DarkroomSpec
 	}
+	cacheTime, customCacheTime := DefaultCacheTime, int64(60)
+	defaultServer := &Server{LogLevel: DefaultLogLevel, Port: DefaultPort, MetricsPort: DefaultMetricsPort, CacheTime: &cacheTime}
+	defaultCircuitBreaker := &CircuitBreaker{
+		Timeout:                DefaultCircuitBreakerTimeout,
+		MaxConcurrentRequests:  DefaultCircuitBreakerMaxConcurrentRequests,
+		RequestVolumeThreshold: DefaultCircuitBreakerRequestVolumeThreshold,
+		SleepWindow:            DefaultCircuitBreakerSleepWindow,
+		ErrorPercentThreshold:  DefaultCircuitBreakerErrorPercentThreshold,
+	}
 	tests := []struct {
 		name string
 		obj  Darkroom
@@ -22,6 +37,71 @@ func TestDarkroom_Default(t *testing.T) {
 			obj:  Darkroom{},
 			want: fields{Spec: DarkroomSpec{
 				Version: "latest",
+				Source:  Source{CircuitBreaker: defaultCircuitBreaker},
+				Server:  defaultServer,
+			}},
+		},
+		{
+			name: "DefaultCredentialsSecretRefKeys",
+			obj: Darkroom{Spec: DarkroomSpec{
+				Version: "v1",
+				Source: Source{
+					Type:   S3,
+					Bucket: &Bucket{CredentialsSecretRef: &CredentialsSecretRef{Name: "creds", SecretKeyKey: "secret"}},
+				},
+			}},
+			want: fields{Spec: DarkroomSpec{
+				Version: "v1",
+				Source: Source{
+					Type: S3,
+					Bucket: &Bucket{CredentialsSecretRef: &CredentialsSecretRef{
+						Name:               "creds",
+						AccessKeyKey:       DefaultAccessKeyKey,
+						SecretKeyKey:       "secret",
+						CredentialsJsonKey: DefaultCredentialsJsonKey,
+						SASTokenKey:        DefaultSASTokenKey,
+					}},
+					CircuitBreaker: defaultCircuitBreaker,
+				},
+				Server: defaultServer,
+			}},
+		},
+		{
+			name: "DefaultTLS",
+			obj: Darkroom{
+				ObjectMeta: v1.ObjectMeta{Name: "darkroom"},
+				Spec: DarkroomSpec{
+					Version: "v1",
+					TLS:     &TLS{IssuerRef: IssuerRef{Name: "letsencrypt"}},
+				},
+			},
+			want: fields{Spec: DarkroomSpec{
+				Version: "v1",
+				Source:  Source{CircuitBreaker: defaultCircuitBreaker},
+				Server:  defaultServer,
+				TLS: &TLS{
+					IssuerRef:  IssuerRef{Name: "letsencrypt", Kind: "Issuer", Group: "cert-manager.io"},
+					SecretName: "darkroom-tls",
+				},
+			}},
+		},
+		{
+			name: "KeepServerTuning",
+			obj: Darkroom{Spec: DarkroomSpec{
+				Version: "v1",
+				Source:  Source{CircuitBreaker: &CircuitBreaker{Timeout: 1000, ErrorPercentThreshold: 50}},
+				Server:  &Server{Debug: true, LogLevel: "debug", CacheTime: &customCacheTime},
+			}},
+			want: fields{Spec: DarkroomSpec{
+				Version: "v1",
+				Source: Source{CircuitBreaker: &CircuitBreaker{
+					Timeout:                1000,
+					MaxConcurrentRequests:  DefaultCircuitBreakerMaxConcurrentRequests,
+					RequestVolumeThreshold: DefaultCircuitBreakerRequestVolumeThreshold,
+					SleepWindow:            DefaultCircuitBreakerSleepWindow,
+					ErrorPercentThreshold:  50,
+				}},
+				Server: &Server{Debug: true, LogLevel: "debug", Port: DefaultPort, MetricsPort: DefaultMetricsPort, CacheTime: &customCacheTime},
 			}},
 		},
 	}
@@ -35,7 +115,44 @@ func TestDarkroom_Default(t *testing.T) {
 	}
 }
 
+func TestDarkroom_ResolveVersion(t *testing.T) {
+	for version, want := range map[string]string{"": "0.1.0", "latest": "0.1.0", "0.0.9": "0.0.9"} {
+		d := Darkroom{Spec: DarkroomSpec{Version: version}}
+		d.ResolveVersion("0.1.0")
+		if d.Spec.Version != want {
+			t.Errorf("ResolveVersion() version %q got = %v, want %v", version, d.Spec.Version, want)
+		}
+	}
+}
+
+func TestDarkroom_ImageReference(t *testing.T) {
+	tests := []struct {
+		name  string
+		image *Image
+		want  string
+	}{
+		{name: "Default", want: "registry.internal:5000/gojektech/darkroom:0.1.0"},
+		{name: "Repository", image: &Image{Repository: "mirror/darkroom"}, want: "mirror/darkroom:0.1.0"},
+		{name: "Tag", image: &Image{Tag: "0.2.0"}, want: "registry.internal:5000/gojektech/darkroom:0.2.0"},
+		{
+			name:  "Digest",
+			image: &Image{Repository: "mirror/darkroom", Digest: "sha256:0123456789abcdef0123456789abcdef"},
+			want:  "mirror/darkroom@sha256:0123456789abcdef0123456789abcdef",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Darkroom{Spec: DarkroomSpec{Version: "0.1.0", Image: tt.image}}
+			if got := d.ImageReference("registry.internal:5000/gojektech/darkroom"); got != tt.want {
+				t.Errorf("ImageReference() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestDarkroom_ValidateCreate(t *testing.T) {
+	replicas, negativeReplicas := int32(3), int32(-1)
+	maxSurge, zero, overMaxUnavailable := intstr.FromString("25%"), intstr.FromInt(0), intstr.FromString("150%")
 	type fields struct {
 		Spec DarkroomSpec
 	}
@@ -45,119 +162,1209 @@ func TestDarkroom_ValidateCreate(t *testing.T) {
 		wantErr bool
 	}{
 		{
-			name: "WebFolderCreate",
+			name: "WebFolderCreate",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "S3Create",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   S3,
+						Bucket: &Bucket{AccessKey: "access", SecretKey: "secret"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "GoogleCloudStorageCreate",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   GoogleCloudStorage,
+						Bucket: &Bucket{CredentialsJson: "{}"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "S3CreateWithCredentialsSecretRef",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   S3,
+						Bucket: &Bucket{CredentialsSecretRef: &CredentialsSecretRef{Name: "creds"}},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "GoogleCloudStorageCreateWithCredentialsSecretRef",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   GoogleCloudStorage,
+						Bucket: &Bucket{CredentialsSecretRef: &CredentialsSecretRef{Name: "creds"}},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "WebFolderHasNoBaseUrl",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "random"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "S3HasNoBucket",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type: S3,
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "S3HasNoAccessKey",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   S3,
+						Bucket: &Bucket{AccessKey: "access"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "S3HasNoSecretKey",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   S3,
+						Bucket: &Bucket{SecretKey: "secret"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "S3HasCredentialsSecretRefAndInlineCredentials",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type: S3,
+						Bucket: &Bucket{
+							AccessKey:            "access",
+							SecretKey:            "secret",
+							CredentialsSecretRef: &CredentialsSecretRef{Name: "creds"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "S3HasCredentialsSecretRefWithoutName",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   S3,
+						Bucket: &Bucket{CredentialsSecretRef: &CredentialsSecretRef{}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "GoogleCloudStorageHasNoBucket",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type: GoogleCloudStorage,
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "GoogleCloudStorageHasNoCredentialsJson",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   GoogleCloudStorage,
+						Bucket: &Bucket{},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "GoogleCloudStorageHasCredentialsSecretRefAndInlineCredentials",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type: GoogleCloudStorage,
+						Bucket: &Bucket{
+							CredentialsJson:      "{}",
+							CredentialsSecretRef: &CredentialsSecretRef{Name: "creds"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "GoogleCloudStorageHasInvalidCredentialsJson",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   GoogleCloudStorage,
+						Bucket: &Bucket{CredentialsJson: "random"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "WebFolderCreateWithTLS",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					TLS: &TLS{IssuerRef: IssuerRef{Name: "letsencrypt"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "WebFolderCreateWithDeployment",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Deployment: &Deployment{
+						Replicas: &replicas,
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+							Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+						},
+						NodeSelector:   map[string]string{"pool": "images"},
+						PodLabels:      map[string]string{"team": "images"},
+						PodAnnotations: map[string]string{"prometheus.io/scrape": "true"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "DeploymentHasNegativeReplicas",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Deployment: &Deployment{Replicas: &negativeReplicas},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "DeploymentHasRequestsAboveLimits",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Deployment: &Deployment{
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+							Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "DeploymentHasReservedPodLabel",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Deployment: &Deployment{PodLabels: map[string]string{SelectorLabel: "other"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "DeploymentHasInvalidTopologySpreadConstraint",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Deployment: &Deployment{
+						TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+							{MaxSkew: 0, WhenUnsatisfiable: corev1.DoNotSchedule},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "DeploymentWithProbesAndRollingUpdate",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Deployment: &Deployment{
+						Probes: &Probes{
+							Liveness:  &corev1.Probe{InitialDelaySeconds: 10},
+							Readiness: &corev1.Probe{Handler: corev1.Handler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(3000)}}},
+						},
+						RollingUpdate: &appsv1.RollingUpdateDeployment{MaxSurge: &maxSurge, MaxUnavailable: &zero},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "DeploymentHasProbeWithMultipleHandlers",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Deployment: &Deployment{
+						Probes: &Probes{Liveness: &corev1.Probe{Handler: corev1.Handler{
+							Exec:      &corev1.ExecAction{Command: []string{"true"}},
+							TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(3000)},
+						}}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "DeploymentHasLivenessProbeWithSuccessThreshold",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Deployment: &Deployment{
+						Probes: &Probes{Liveness: &corev1.Probe{SuccessThreshold: 2}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "DeploymentHasNegativeProbePeriod",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Deployment: &Deployment{
+						Probes: &Probes{Readiness: &corev1.Probe{PeriodSeconds: -1}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "DeploymentHasZeroMaxSurgeAndMaxUnavailable",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Deployment: &Deployment{
+						RollingUpdate: &appsv1.RollingUpdateDeployment{MaxSurge: &zero, MaxUnavailable: &zero},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "DeploymentHasInvalidMaxUnavailable",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Deployment: &Deployment{
+						RollingUpdate: &appsv1.RollingUpdateDeployment{MaxUnavailable: &overMaxUnavailable},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "WebFolderCreateWithDisruptionBudget",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					DisruptionBudget: &DisruptionBudget{MinAvailable: &maxSurge},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "DisruptionBudgetHasMinAvailableAndMaxUnavailable",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					DisruptionBudget: &DisruptionBudget{MinAvailable: &maxSurge, MaxUnavailable: &zero},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "DisruptionBudgetHasInvalidMaxUnavailable",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					DisruptionBudget: &DisruptionBudget{MaxUnavailable: &overMaxUnavailable},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "WebFolderCreateWithNetworkPolicy",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					NetworkPolicy: &NetworkPolicy{IngressNamespaceSelector: &v1.LabelSelector{MatchLabels: map[string]string{"name": "ingress-nginx"}}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "S3CreateWithNetworkPolicy",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   S3,
+						Bucket: &Bucket{Name: "bucket", AccessKey: "access", SecretKey: "secret"},
+					},
+					NetworkPolicy: &NetworkPolicy{IngressNamespaceSelector: &v1.LabelSelector{MatchLabels: map[string]string{"name": "ingress-nginx"}}, EgressCIDRs: []string{"52.0.0.0/8"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "NetworkPolicyHasNoIngressSelector",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					NetworkPolicy: &NetworkPolicy{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "NetworkPolicyHasInvalidEgressCIDR",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					NetworkPolicy: &NetworkPolicy{IngressNamespaceSelector: &v1.LabelSelector{MatchLabels: map[string]string{"name": "ingress-nginx"}}, EgressCIDRs: []string{"52.0.0.0"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "NetworkPolicyHasInvalidPrometheusSelector",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					NetworkPolicy: &NetworkPolicy{
+						IngressNamespaceSelector: &v1.LabelSelector{MatchLabels: map[string]string{"name": "ingress-nginx"}},
+						PrometheusPodSelector:    &v1.LabelSelector{MatchLabels: map[string]string{"app/name/": "prometheus"}},
+					},
+					ServiceMonitor: &ServiceMonitor{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "S3NetworkPolicyHasNoEgressCIDRs",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   S3,
+						Bucket: &Bucket{Name: "bucket", AccessKey: "access", SecretKey: "secret"},
+					},
+					NetworkPolicy: &NetworkPolicy{IngressNamespaceSelector: &v1.LabelSelector{MatchLabels: map[string]string{"name": "ingress-nginx"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ServiceMonitorWithNetworkPolicyHasNoPrometheusSelector",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					NetworkPolicy:  &NetworkPolicy{IngressNamespaceSelector: &v1.LabelSelector{MatchLabels: map[string]string{"name": "ingress-nginx"}}},
+					ServiceMonitor: &ServiceMonitor{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "WebFolderCreateWithServiceMonitorAndNetworkPolicy",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					NetworkPolicy: &NetworkPolicy{
+						IngressNamespaceSelector:    &v1.LabelSelector{MatchLabels: map[string]string{"name": "ingress-nginx"}},
+						PrometheusNamespaceSelector: &v1.LabelSelector{MatchLabels: map[string]string{"name": "monitoring"}},
+					},
+					ServiceMonitor: &ServiceMonitor{},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "WebFolderCreateWithServiceMonitor",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					ServiceMonitor: &ServiceMonitor{Labels: map[string]string{"release": "prometheus"}, Interval: "1m30s"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ServiceMonitorHasInvalidInterval",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					ServiceMonitor: &ServiceMonitor{Interval: "30 seconds"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ServiceMonitorHasInvalidLabel",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					ServiceMonitor: &ServiceMonitor{Labels: map[string]string{"release": "-"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "MetricsPortEqualsPort",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Server: &Server{Port: 9090},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "WebFolderCreateWithOrphanDeletionPolicy",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					DeletionPolicy: DeletionPolicyOrphan,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "UnsupportedDeletionPolicy",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					DeletionPolicy: "Retain",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "WebFolderCreateWithAutoscaling",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Autoscaling: &Autoscaling{
+						MinReplicas: &replicas,
+						MaxReplicas: 10,
+						CustomMetrics: []CustomMetric{
+							{Name: "requests_per_second", TargetAverageValue: resource.MustParse("100")},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "AutoscalingHasMinReplicasAboveMaxReplicas",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Autoscaling: &Autoscaling{MinReplicas: &replicas, MaxReplicas: 2},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "AutoscalingHasNoCustomMetricTarget",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Autoscaling: &Autoscaling{
+						MaxReplicas:   10,
+						CustomMetrics: []CustomMetric{{Name: "requests_per_second"}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "WebFolderCreateWithServerTuning",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:           WebFolder,
+						WebFolderMeta:  WebFolderMeta{BaseURL: "https://example.com"},
+						CircuitBreaker: &CircuitBreaker{Timeout: 1000, ErrorPercentThreshold: 50},
+					},
+					Server:   &Server{LogLevel: "debug", Port: 8000},
+					ExtraEnv: map[string]string{"SOURCE_PATHPREFIX": "/images"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ServerHasUnsupportedLogLevel",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Server: &Server{LogLevel: "verbose"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ServerHasPortOutOfRange",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Server: &Server{Port: 70000},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "CircuitBreakerHasErrorPercentThresholdOutOfRange",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:           WebFolder,
+						WebFolderMeta:  WebFolderMeta{BaseURL: "https://example.com"},
+						CircuitBreaker: &CircuitBreaker{ErrorPercentThreshold: 101},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ExtraEnvHasInvalidName",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					ExtraEnv: map[string]string{"1SOURCE=": "value"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "WebFolderHasBucket",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+						Bucket:        &Bucket{Name: "bucket"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "S3HasBaseUrl",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          S3,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+						Bucket:        &Bucket{AccessKey: "access", SecretKey: "secret"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "S3CreateWithMinIOEndpoint",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type: S3,
+						Bucket: &Bucket{
+							AccessKey:          "access",
+							SecretKey:          "secret",
+							Region:             "us-east-1",
+							Endpoint:           "https://minio.storage.svc:9000",
+							PathStyle:          true,
+							InsecureSkipVerify: true,
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "S3HasInvalidRegion",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   S3,
+						Bucket: &Bucket{AccessKey: "access", SecretKey: "secret", Region: "EU West"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "S3HasEndpointWithUnsupportedScheme",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   S3,
+						Bucket: &Bucket{AccessKey: "access", SecretKey: "secret", Endpoint: "ftp://minio:9000"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "S3HasInsecureSkipVerifyWithoutHttpsEndpoint",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type: S3,
+						Bucket: &Bucket{
+							AccessKey:          "access",
+							SecretKey:          "secret",
+							Endpoint:           "http://minio:9000",
+							InsecureSkipVerify: true,
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "S3HasInvalidEndpointWithCredentialsSecretRef",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type: S3,
+						Bucket: &Bucket{
+							CredentialsSecretRef: &CredentialsSecretRef{Name: "creds"},
+							Endpoint:             "minio:9000",
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "GoogleCloudStorageHasEndpoint",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   GoogleCloudStorage,
+						Bucket: &Bucket{CredentialsJson: `{}`, Endpoint: "https://minio:9000"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "AzureBlobStorageCreate",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:  AzureBlobStorage,
+						Azure: &AzureBlob{AccountName: "darkroom", Container: "images", SASToken: "sv=2020&sig=abc"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "AzureBlobStorageCreateWithCredentialsSecretRef",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type: AzureBlobStorage,
+						Azure: &AzureBlob{
+							AccountName:          "darkroom",
+							Container:            "images",
+							CredentialsSecretRef: &CredentialsSecretRef{Name: "creds"},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "AzureBlobStorageHasNoAzure",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{Type: AzureBlobStorage},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "AzureBlobStorageHasInvalidAccountName",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:  AzureBlobStorage,
+						Azure: &AzureBlob{AccountName: "Dark-Room", Container: "images", SASToken: "token"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "AzureBlobStorageHasInvalidContainer",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:  AzureBlobStorage,
+						Azure: &AzureBlob{AccountName: "darkroom", Container: "images--old", SASToken: "token"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "AzureBlobStorageHasNoSASToken",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:  AzureBlobStorage,
+						Azure: &AzureBlob{AccountName: "darkroom", Container: "images"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "AzureBlobStorageHasCredentialsSecretRefAndSASToken",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type: AzureBlobStorage,
+						Azure: &AzureBlob{
+							AccountName:          "darkroom",
+							Container:            "images",
+							SASToken:             "token",
+							CredentialsSecretRef: &CredentialsSecretRef{Name: "creds"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "AzureBlobStorageHasBucket",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   AzureBlobStorage,
+						Bucket: &Bucket{Name: "bucket"},
+						Azure:  &AzureBlob{AccountName: "darkroom", Container: "images", SASToken: "token"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "S3HasAzure",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   S3,
+						Bucket: &Bucket{Name: "bucket", AccessKey: "access", SecretKey: "secret"},
+						Azure:  &AzureBlob{AccountName: "darkroom", Container: "images"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "WebFolderCreateWithFallbackSources",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					FallbackSources: []Source{
+						{
+							Type:   S3,
+							Bucket: &Bucket{Name: "bucket", CredentialsSecretRef: &CredentialsSecretRef{Name: "creds"}},
+						},
+						{
+							Type:          WebFolder,
+							WebFolderMeta: WebFolderMeta{BaseURL: "https://legacy.example.com"},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "FallbackSourceIsInvalid",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					FallbackSources: []Source{{Type: S3}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "FallbackSourceHasInlineCredentials",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					FallbackSources: []Source{
+						{Type: S3, Bucket: &Bucket{Name: "bucket", AccessKey: "access", SecretKey: "secret"}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "FallbackSourceHasCircuitBreaker",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					FallbackSources: []Source{
+						{
+							Type:           WebFolder,
+							WebFolderMeta:  WebFolderMeta{BaseURL: "https://legacy.example.com"},
+							CircuitBreaker: &CircuitBreaker{Timeout: 1000},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "WebFolderCreateWithHeadersAndBasicAuth",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type: WebFolder,
+						WebFolderMeta: WebFolderMeta{
+							BaseURL:            "https://example.com",
+							Headers:            map[string]SecretKeyRef{"X-Api-Key": {Name: "origin", Key: "apiKey"}},
+							BasicAuthSecretRef: &BasicAuthSecretRef{Name: "origin-auth"},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "WebFolderHasUnsupportedScheme",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "ftp://example.com/images"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "WebFolderHasNoHost",
 			fields: fields{
 				Spec: DarkroomSpec{
 					Source: Source{
 						Type:          WebFolder,
-						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+						WebFolderMeta: WebFolderMeta{BaseURL: "https:///images"},
 					},
 				},
 			},
-			wantErr: false,
+			wantErr: true,
 		},
 		{
-			name: "S3Create",
+			name: "WebFolderHasInvalidHeaderName",
 			fields: fields{
 				Spec: DarkroomSpec{
 					Source: Source{
-						Type:   S3,
-						Bucket: &Bucket{AccessKey: "access", SecretKey: "secret"},
+						Type: WebFolder,
+						WebFolderMeta: WebFolderMeta{
+							BaseURL: "https://example.com",
+							Headers: map[string]SecretKeyRef{"X_Api Key": {Name: "origin", Key: "apiKey"}},
+						},
 					},
 				},
 			},
-			wantErr: false,
+			wantErr: true,
 		},
 		{
-			name: "GoogleCloudStorageCreate",
+			name: "WebFolderHasDuplicateHeaderNames",
 			fields: fields{
 				Spec: DarkroomSpec{
 					Source: Source{
-						Type:   GoogleCloudStorage,
-						Bucket: &Bucket{CredentialsJson: "{}"},
+						Type: WebFolder,
+						WebFolderMeta: WebFolderMeta{
+							BaseURL: "https://example.com",
+							Headers: map[string]SecretKeyRef{
+								"X-Api-Key": {Name: "origin", Key: "apiKey"},
+								"x-api-key": {Name: "origin", Key: "apiKey"},
+							},
+						},
 					},
 				},
 			},
-			wantErr: false,
+			wantErr: true,
 		},
 		{
-			name: "WebFolderHasNoBaseUrl",
+			name: "WebFolderHasHeaderWithoutSecretKey",
 			fields: fields{
 				Spec: DarkroomSpec{
 					Source: Source{
-						Type:          WebFolder,
-						WebFolderMeta: WebFolderMeta{BaseURL: "random"},
+						Type: WebFolder,
+						WebFolderMeta: WebFolderMeta{
+							BaseURL: "https://example.com",
+							Headers: map[string]SecretKeyRef{"X-Api-Key": {Name: "origin"}},
+						},
 					},
 				},
 			},
 			wantErr: true,
 		},
 		{
-			name: "S3HasNoBucket",
+			name: "WebFolderHasBasicAuthSecretRefWithoutName",
 			fields: fields{
 				Spec: DarkroomSpec{
 					Source: Source{
-						Type: S3,
+						Type: WebFolder,
+						WebFolderMeta: WebFolderMeta{
+							BaseURL:            "https://example.com",
+							BasicAuthSecretRef: &BasicAuthSecretRef{},
+						},
 					},
 				},
 			},
 			wantErr: true,
 		},
 		{
-			name: "S3HasNoAccessKey",
+			name: "S3HasHeaders",
 			fields: fields{
 				Spec: DarkroomSpec{
 					Source: Source{
-						Type:   S3,
-						Bucket: &Bucket{AccessKey: "access"},
+						Type:          S3,
+						Bucket:        &Bucket{Name: "bucket", AccessKey: "access", SecretKey: "secret"},
+						WebFolderMeta: WebFolderMeta{Headers: map[string]SecretKeyRef{"X-Api-Key": {Name: "origin", Key: "apiKey"}}},
 					},
 				},
 			},
 			wantErr: true,
 		},
 		{
-			name: "S3HasNoSecretKey",
+			name: "WebFolderCreateWithImage",
 			fields: fields{
 				Spec: DarkroomSpec{
 					Source: Source{
-						Type:   S3,
-						Bucket: &Bucket{SecretKey: "secret"},
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+					},
+					Image: &Image{
+						Repository:       "registry.internal:5000/gojektech/darkroom",
+						Digest:           "sha256:0123456789abcdef0123456789abcdef",
+						PullPolicy:       corev1.PullIfNotPresent,
+						ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ImageHasTagAndDigest",
+			fields: fields{
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
 					},
+					Image: &Image{Tag: "0.1.0", Digest: "sha256:0123456789abcdef0123456789abcdef"},
 				},
 			},
 			wantErr: true,
 		},
 		{
-			name: "GoogleCloudStorageHasNoBucket",
+			name: "ImageHasInvalidRepository",
 			fields: fields{
 				Spec: DarkroomSpec{
 					Source: Source{
-						Type: GoogleCloudStorage,
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
 					},
+					Image: &Image{Repository: "Registry/Darkroom"},
 				},
 			},
 			wantErr: true,
 		},
 		{
-			name: "GoogleCloudStorageHasNoCredentialsJson",
+			name: "ImageHasInvalidDigest",
 			fields: fields{
 				Spec: DarkroomSpec{
 					Source: Source{
-						Type:   GoogleCloudStorage,
-						Bucket: &Bucket{},
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
 					},
+					Image: &Image{Digest: "0123456789abcdef"},
 				},
 			},
 			wantErr: true,
 		},
 		{
-			name: "GoogleCloudStorageHasInvalidCredentialsJson",
+			name: "TLSAndIngressTLSSecretName",
 			fields: fields{
 				Spec: DarkroomSpec{
 					Source: Source{
-						Type:   GoogleCloudStorage,
-						Bucket: &Bucket{CredentialsJson: "random"},
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
 					},
+					TLS:     &TLS{IssuerRef: IssuerRef{Name: "letsencrypt"}},
+					Ingress: &Ingress{TLSSecretName: "custom-tls"},
 				},
 			},
 			wantErr: true,
@@ -184,6 +1391,29 @@ func TestDarkroom_ValidateUpdate(t *testing.T) {
 		Name:      "sample",
 		Namespace: "default",
 	}
+	migrating := v1.ObjectMeta{
+		Name:        "sample",
+		Namespace:   "default",
+		Annotations: map[string]string{MigrateSourceAnnotation: "true"},
+	}
+	now := v1.Now()
+	deleting := v1.ObjectMeta{
+		Name:              "sample",
+		Namespace:         "default",
+		DeletionTimestamp: &now,
+	}
+	webFolder := DarkroomSpec{
+		Source: Source{
+			Type:          WebFolder,
+			WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+		},
+	}
+	s3 := DarkroomSpec{
+		Source: Source{
+			Type:   S3,
+			Bucket: &Bucket{Name: "bucket", AccessKey: "access", SecretKey: "secret"},
+		},
+	}
 	type fields struct {
 		TypeMeta   v1.TypeMeta
 		ObjectMeta v1.ObjectMeta
@@ -193,10 +1423,10 @@ func TestDarkroom_ValidateUpdate(t *testing.T) {
 		old runtime.Object
 	}
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		wantErr bool
+		name     string
+		fields   fields
+		args     args
+		wantErrs int
 	}{
 		{
 			name: "Success",
@@ -205,7 +1435,308 @@ func TestDarkroom_ValidateUpdate(t *testing.T) {
 				ObjectMeta: om,
 				Spec:       DarkroomSpec{},
 			},
-			wantErr: false,
+			args:     args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om}},
+			wantErrs: 0,
+		},
+		{
+			name: "ChangeBaseURL",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com/images"},
+					},
+				},
+			},
+			args:     args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: webFolder}},
+			wantErrs: 0,
+		},
+		{
+			name: "FailOldIsNotADarkroom",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec:       webFolder,
+			},
+			args:     args{old: nil},
+			wantErrs: 1,
+		},
+		{
+			name: "FailCreateChecks",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "random"},
+					},
+					Server: &Server{LogLevel: "verbose"},
+				},
+			},
+			args:     args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: webFolder}},
+			wantErrs: 2,
+		},
+		{
+			name: "RequestReconcile",
+			fields: fields{
+				TypeMeta: tm,
+				ObjectMeta: v1.ObjectMeta{
+					Name:        "sample",
+					Namespace:   "default",
+					Annotations: map[string]string{ReconcileAtAnnotation: "2021-08-01T10:00:00Z"},
+				},
+				Spec: webFolder,
+			},
+			args:     args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: webFolder}},
+			wantErrs: 0,
+		},
+		{
+			name: "FailReconcileAtIsNotATimestamp",
+			fields: fields{
+				TypeMeta: tm,
+				ObjectMeta: v1.ObjectMeta{
+					Name:        "sample",
+					Namespace:   "default",
+					Annotations: map[string]string{ReconcileAtAnnotation: "now"},
+				},
+				Spec: webFolder,
+			},
+			args:     args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: webFolder}},
+			wantErrs: 1,
+		},
+		{
+			name: "KeepViolationAdmittedBefore",
+			fields: fields{
+				TypeMeta: tm,
+				ObjectMeta: v1.ObjectMeta{
+					Name:        "sample",
+					Namespace:   "default",
+					Annotations: map[string]string{PausedAnnotation: "true"},
+				},
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "ftp://example.com"},
+					},
+				},
+			},
+			args: args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: DarkroomSpec{
+				Source: Source{
+					Type:          WebFolder,
+					WebFolderMeta: WebFolderMeta{BaseURL: "ftp://example.com"},
+				},
+			}}},
+			wantErrs: 0,
+		},
+		{
+			name: "FailChangeViolationAdmittedBefore",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "ftp://example.com/images"},
+					},
+					Server: &Server{LogLevel: "verbose"},
+				},
+			},
+			args: args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: DarkroomSpec{
+				Source: Source{
+					Type:          WebFolder,
+					WebFolderMeta: WebFolderMeta{BaseURL: "ftp://example.com"},
+				},
+			}}},
+			wantErrs: 2,
+		},
+		{
+			name: "KeepInlineCredentialsAdmittedBefore",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type: S3,
+						Bucket: &Bucket{Name: "bucket", AccessKey: "access", SecretKey: "secret",
+							CredentialsSecretRef: &CredentialsSecretRef{Name: "credentials"}},
+					},
+					Server: &Server{LogLevel: "debug"},
+				},
+			},
+			args: args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: DarkroomSpec{
+				Source: Source{
+					Type: S3,
+					Bucket: &Bucket{Name: "bucket", AccessKey: "access", SecretKey: "secret",
+						CredentialsSecretRef: &CredentialsSecretRef{Name: "credentials"}},
+				},
+			}}},
+			wantErrs: 0,
+		},
+		{
+			name: "FailChangeInlineCredentialsAdmittedBefore",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type: S3,
+						Bucket: &Bucket{Name: "bucket", AccessKey: "other-access", SecretKey: "secret",
+							CredentialsSecretRef: &CredentialsSecretRef{Name: "credentials"}},
+					},
+				},
+			},
+			args: args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: DarkroomSpec{
+				Source: Source{
+					Type: S3,
+					Bucket: &Bucket{Name: "bucket", AccessKey: "access", SecretKey: "secret",
+						CredentialsSecretRef: &CredentialsSecretRef{Name: "credentials"}},
+				},
+			}}},
+			wantErrs: 1,
+		},
+		{
+			name: "FailChangeSourceType",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec:       webFolder,
+			},
+			args:     args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: s3}},
+			wantErrs: 1,
+		},
+		{
+			name: "ChangeSourceTypeWithMigrationAnnotation",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: migrating,
+				Spec:       webFolder,
+			},
+			args:     args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: s3}},
+			wantErrs: 0,
+		},
+		{
+			name: "FailChangeSourceTypeWithStaleBucket",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: migrating,
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "https://example.com"},
+						Bucket:        s3.Source.Bucket,
+					},
+				},
+			},
+			args:     args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: s3}},
+			wantErrs: 1,
+		},
+		{
+			name: "FailChangeSourceTypeWithStaleBaseURL",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: migrating,
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          S3,
+						WebFolderMeta: webFolder.Source.WebFolderMeta,
+						Bucket:        s3.Source.Bucket,
+					},
+				},
+			},
+			args:     args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: webFolder}},
+			wantErrs: 1,
+		},
+		{
+			name: "FailChangeBucketName",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   S3,
+						Bucket: &Bucket{Name: "other-bucket", AccessKey: "access", SecretKey: "secret"},
+					},
+				},
+			},
+			args:     args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: s3}},
+			wantErrs: 1,
+		},
+		{
+			name: "ChangeBucketNameWithMigrationAnnotation",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: migrating,
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:   S3,
+						Bucket: &Bucket{Name: "other-bucket", AccessKey: "access", SecretKey: "secret"},
+					},
+				},
+			},
+			args:     args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: s3}},
+			wantErrs: 0,
+		},
+		{
+			name: "FailChangeAzureContainer",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:  AzureBlobStorage,
+						Azure: &AzureBlob{AccountName: "darkroom", Container: "other-images", SASToken: "token"},
+					},
+				},
+			},
+			args: args{old: &Darkroom{TypeMeta: tm, ObjectMeta: om, Spec: DarkroomSpec{
+				Source: Source{
+					Type:  AzureBlobStorage,
+					Azure: &AzureBlob{AccountName: "darkroom", Container: "images", SASToken: "token"},
+				},
+			}}},
+			wantErrs: 1,
+		},
+		{
+			name: "DeletingRemovesFinalizers",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: deleting,
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "ftp://example.com"},
+					},
+				},
+			},
+			args: args{old: &Darkroom{
+				TypeMeta: tm,
+				ObjectMeta: v1.ObjectMeta{
+					Name:              "sample",
+					Namespace:         "default",
+					DeletionTimestamp: &now,
+					Finalizers:        []string{CleanupFinalizer},
+				},
+				Spec: DarkroomSpec{
+					Source: Source{
+						Type:          WebFolder,
+						WebFolderMeta: WebFolderMeta{BaseURL: "ftp://example.com"},
+					},
+				},
+			}},
+			wantErrs: 0,
+		},
+		{
+			name: "FailChangeSpecWhileDeleting",
+			fields: fields{
+				TypeMeta:   tm,
+				ObjectMeta: deleting,
+				Spec:       webFolder,
+			},
+			args:     args{old: &Darkroom{TypeMeta: tm, ObjectMeta: deleting, Spec: s3}},
+			wantErrs: 1,
 		},
 	}
 	for _, tt := range tests {
@@ -215,8 +1746,15 @@ func TestDarkroom_ValidateUpdate(t *testing.T) {
 				ObjectMeta: tt.fields.ObjectMeta,
 				Spec:       tt.fields.Spec,
 			}
-			if err := d.ValidateUpdate(tt.args.old); (err != nil) != tt.wantErr {
-				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			err := d.ValidateUpdate(tt.args.old)
+			if (err != nil) != (tt.wantErrs > 0) {
+				t.Fatalf("ValidateUpdate() error = %v, wantErrs %v", err, tt.wantErrs)
+			}
+			var statusErr *apierrors.StatusError
+			if errors.As(err, &statusErr) && statusErr.ErrStatus.Details != nil {
+				if got := len(statusErr.ErrStatus.Details.Causes); got != tt.wantErrs {
+					t.Errorf("ValidateUpdate() causes = %v, wantErrs %v", statusErr.ErrStatus.Details.Causes, tt.wantErrs)
+				}
 			}
 		})
 	}
@@ -252,6 +1790,30 @@ func TestDarkroom_ValidateDelete(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Protected",
+			fields: fields{
+				TypeMeta: tm,
+				ObjectMeta: v1.ObjectMeta{
+					Name:        "sample",
+					Namespace:   "default",
+					Annotations: map[string]string{ProtectedAnnotation: "true"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "NoLongerProtected",
+			fields: fields{
+				TypeMeta: tm,
+				ObjectMeta: v1.ObjectMeta{
+					Name:        "sample",
+					Namespace:   "default",
+					Annotations: map[string]string{ProtectedAnnotation: "false"},
+				},
+			},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -267,3 +1829,17 @@ func TestDarkroom_ValidateDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestFieldPathSegments(t *testing.T) {
+	for p, want := range map[string][]string{
+		"spec.source.baseUrl":                   {"spec", "source", "baseUrl"},
+		"spec.fallbackSources[1].bucket.name":   {"spec", "fallbackSources", "1", "bucket", "name"},
+		"spec.source.headers[X-Api-Key].name":   {"spec", "source", "headers", "X-Api-Key", "name"},
+		"spec.deployment.nodeSelector[pool]":    {"spec", "deployment", "nodeSelector", "pool"},
+		"spec.image.imagePullSecrets[0][extra]": {"spec", "image", "imagePullSecrets", "0", "extra"},
+	} {
+		if got := fieldPathSegments(p); !reflect.DeepEqual(got, want) {
+			t.Errorf("fieldPathSegments(%q) got = %v, want %v", p, got, want)
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package v1alpha1
 
 import (
+	"fmt"
 	"net/url"
+	"regexp"
+	"sort"
+	"strings"
 
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
@@ -10,17 +15,125 @@ const (
 	WebFolder Type = "WebFolder"
 )
 
+// headerNameRegexp restricts header names to the characters that map onto environment variable names
+var headerNameRegexp = regexp.MustCompile(`^[A-Za-z0-9]+(-[A-Za-z0-9]+)*$`)
+
 type WebFolderMeta struct {
 	BaseURL string `json:"baseUrl,omitempty"`
+	// Headers are sent with every request to BaseURL, e.g. an API key, mapping the header name to the Secret key
+	// holding its value
+	// +optional
+	Headers map[string]SecretKeyRef `json:"headers,omitempty"`
+	// BasicAuthSecretRef references a Secret in the Darkroom namespace holding the username and password
+	// used to authenticate with BaseURL, e.g. a kubernetes.io/basic-auth Secret
+	// +optional
+	BasicAuthSecretRef *BasicAuthSecretRef `json:"basicAuthSecretRef,omitempty"`
+}
+
+// SecretKeyRef references a key of a Secret in the Darkroom namespace
+type SecretKeyRef struct {
+	// Name of the Secret
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Key of the Secret
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
 }
 
-func (d *Darkroom) validateWebFolder() *field.Error {
-	if _, err := url.ParseRequestURI(d.Spec.Source.BaseURL); err != nil {
-		return field.Invalid(
-			field.NewPath("spec").Child("source").Child("baseUrl"),
-			d.Spec.Source.BaseURL,
+// BasicAuthSecretRef maps the basic auth credentials to keys of a Secret
+type BasicAuthSecretRef struct {
+	// Name of the Secret in the Darkroom namespace
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// UsernameKey is the key of the Secret holding the username
+	// +kubebuilder:default=username
+	// +optional
+	UsernameKey string `json:"usernameKey,omitempty"`
+	// PasswordKey is the key of the Secret holding the password
+	// +kubebuilder:default=password
+	// +optional
+	PasswordKey string `json:"passwordKey,omitempty"`
+}
+
+func (r *BasicAuthSecretRef) Default() {
+	if r.UsernameKey == "" {
+		r.UsernameKey = DefaultUsernameKey
+	}
+	if r.PasswordKey == "" {
+		r.PasswordKey = DefaultPasswordKey
+	}
+}
+
+// HeaderNames returns the names of the Headers in a stable order
+func (m *WebFolderMeta) HeaderNames() []string {
+	names := make([]string, 0, len(m.Headers))
+	for name := range m.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Source) validateWebFolder(path *field.Path) field.ErrorList {
+	if s.Bucket != nil {
+		return field.ErrorList{field.Forbidden(
+			path.Child("bucket"),
+			"may not be set with Type WebFolder",
+		)}
+	}
+	u, err := url.ParseRequestURI(s.BaseURL)
+	if err != nil {
+		return field.ErrorList{field.Invalid(
+			path.Child("baseUrl"),
+			s.BaseURL,
 			err.Error(),
-		)
+		)}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return field.ErrorList{field.Invalid(path.Child("baseUrl"), s.BaseURL, "scheme must be http or https")}
+	}
+	if u.Host == "" {
+		return field.ErrorList{field.Invalid(path.Child("baseUrl"), s.BaseURL, "host is required")}
+	}
+
+	var allErrs field.ErrorList
+	seen := map[string]bool{}
+	for _, name := range s.HeaderNames() {
+		p := path.Child("headers").Key(name)
+		if !headerNameRegexp.MatchString(name) {
+			allErrs = append(allErrs, field.Invalid(p, name, "must consist of alphanumeric characters or single '-' between them"))
+		} else if seen[strings.ToLower(name)] {
+			allErrs = append(allErrs, field.Duplicate(p, name))
+		}
+		seen[strings.ToLower(name)] = true
+		allErrs = append(allErrs, validateSecretKeyRef(p, s.Headers[name])...)
+	}
+	if r := s.BasicAuthSecretRef; r != nil && r.Name == "" {
+		allErrs = append(allErrs, field.Required(path.Child("basicAuthSecretRef").Child("name"), "name of the Secret is required"))
+	}
+	return allErrs
+}
+
+func validateSecretKeyRef(path *field.Path, ref SecretKeyRef) field.ErrorList {
+	var allErrs field.ErrorList
+	if ref.Name == "" {
+		allErrs = append(allErrs, field.Required(path.Child("name"), "name of the Secret is required"))
+	}
+	for _, msg := range validation.IsConfigMapKey(ref.Key) {
+		allErrs = append(allErrs, field.Invalid(path.Child("key"), ref.Key, msg))
+	}
+	return allErrs
+}
+
+// validateNoWebFolderMeta forbids the fields of WebFolderMeta on other sources, e.g. left over from a migration
+func (s *Source) validateNoWebFolderMeta(path *field.Path) *field.Error {
+	switch {
+	case s.BaseURL != "":
+		return field.Forbidden(path.Child("baseUrl"), fmt.Sprintf("may not be set with Type %s", s.Type))
+	case len(s.Headers) > 0:
+		return field.Forbidden(path.Child("headers"), fmt.Sprintf("may not be set with Type %s", s.Type))
+	case s.BasicAuthSecretRef != nil:
+		return field.Forbidden(path.Child("basicAuthSecretRef"), fmt.Sprintf("may not be set with Type %s", s.Type))
 	}
 	return nil
 }
@@ -0,0 +1,4 @@
+package v1beta1
+
+// Hub marks v1beta1 as the version the other Darkroom API versions convert through
+func (*Darkroom) Hub() {}
@@ -0,0 +1,74 @@
+package v1beta1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// Ingress configures how the Domains of a Darkroom are routed to its Service
+type Ingress struct {
+	// ClassName of the IngressClass handling the generated Ingress
+	// +optional
+	ClassName *string `json:"className,omitempty"`
+	// Annotations added to the generated Ingress or HTTPRoute
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// TLSSecretName is the Secret holding the certificate served for the Domains
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+	// GatewayRef routes the Domains through a Gateway API HTTPRoute attached to the referenced Gateway
+	// instead of an Ingress, when the Gateway API CRDs are installed. Without them an Ingress is rendered
+	// and a GatewayAPIUnavailable warning Event is recorded.
+	// +optional
+	GatewayRef *GatewayRef `json:"gatewayRef,omitempty"`
+}
+
+// GatewayRef references a Gateway API Gateway
+type GatewayRef struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Namespace of the Gateway, defaults to the Darkroom namespace
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// TLS requests a cert-manager Certificate covering the Domains of a Darkroom
+type TLS struct {
+	IssuerRef IssuerRef `json:"issuerRef"`
+	// SecretName of the Secret the certificate is stored in, defaults to <name>-tls
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// IssuerRef references the cert-manager issuer signing the certificate
+type IssuerRef struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +kubebuilder:default=Issuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// +kubebuilder:default=cert-manager.io
+	// +optional
+	Group string `json:"group,omitempty"`
+}
+
+// NetworkPolicy restricts the traffic of the darkroom pods to the ingress controller, Prometheus, DNS and the
+// source origins
+type NetworkPolicy struct {
+	// IngressNamespaceSelector selects the namespaces of the ingress controller allowed to reach darkroom
+	// +optional
+	IngressNamespaceSelector *metav1.LabelSelector `json:"ingressNamespaceSelector,omitempty"`
+	// IngressPodSelector selects the ingress controller pods allowed to reach darkroom
+	// +optional
+	IngressPodSelector *metav1.LabelSelector `json:"ingressPodSelector,omitempty"`
+	// EgressCIDRs are the addresses of the source origins, required for S3, GoogleCloudStorage and
+	// AzureBlobStorage sources. A WebFolder origin given by IP address is allowed without them, one given
+	// by host name is reachable at these addresses or, without them, at any address on its port.
+	// +optional
+	EgressCIDRs []string `json:"egressCIDRs,omitempty"`
+	// PrometheusNamespaceSelector selects the namespaces of the Prometheus allowed to scrape the metrics port
+	// of darkroom, it or PrometheusPodSelector is required when a ServiceMonitor is requested
+	// +optional
+	PrometheusNamespaceSelector *metav1.LabelSelector `json:"prometheusNamespaceSelector,omitempty"`
+	// PrometheusPodSelector selects the Prometheus pods allowed to scrape the metrics port of darkroom
+	// +optional
+	PrometheusPodSelector *metav1.LabelSelector `json:"prometheusPodSelector,omitempty"`
+}
@@ -0,0 +1,124 @@
+package v1beta1
+
+// Source locates the images served by darkroom. Credentials are only read from Secrets.
+type Source struct {
+	// Type specifies storage backend to use with darkroom.
+	// Valid values are:
+	// - "WebFolder": simple storage backend to serve images from a hosted image source;
+	// - "S3": storage backend to serve images from S3 backend;
+	// - "GoogleCloudStorage": storage backend to serve images from GoogleCloudStorage backend;
+	// - "AzureBlobStorage": storage backend to serve images from an Azure Blob Storage container;
+	Type Type `json:"type"`
+
+	// WebFolder locates the origin of the WebFolder source
+	// +optional
+	WebFolder *WebFolderSource `json:"webFolder,omitempty"`
+	// Bucket locates the bucket of the S3 and GoogleCloudStorage sources
+	// +optional
+	Bucket *Bucket `json:"bucket,omitempty"`
+	// Azure locates the container of the AzureBlobStorage source
+	// +optional
+	Azure *AzureBlob `json:"azure,omitempty"`
+	// CredentialsSecretRef references a Secret in the Darkroom namespace holding the credentials of the
+	// bucket or container
+	// +optional
+	CredentialsSecretRef *CredentialsSecretRef `json:"credentialsSecretRef,omitempty"`
+
+	// +kubebuilder:default="/"
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// CircuitBreaker tunes the circuit breaker guarding the requests to the source
+	// +optional
+	CircuitBreaker *CircuitBreaker `json:"circuitBreaker,omitempty"`
+}
+
+// WebFolderSource locates images hosted behind a base URL
+type WebFolderSource struct {
+	BaseURL string `json:"baseUrl"`
+	// Headers are sent with every request to BaseURL, e.g. an API key, mapping the header name to the Secret key
+	// holding its value
+	// +optional
+	Headers map[string]SecretKeyRef `json:"headers,omitempty"`
+	// BasicAuthSecretRef references a Secret in the Darkroom namespace holding the username and password
+	// used to authenticate with BaseURL, e.g. a kubernetes.io/basic-auth Secret
+	// +optional
+	BasicAuthSecretRef *BasicAuthSecretRef `json:"basicAuthSecretRef,omitempty"`
+}
+
+type Bucket struct {
+	// +kubebuilder:validation:MinLength=3
+	Name string `json:"name"`
+	// Region of the S3 bucket
+	// +kubebuilder:validation:Pattern=`^[a-z0-9-]+$`
+	// +optional
+	Region string `json:"region,omitempty"`
+	// Endpoint is the URL of an S3-compatible service, e.g. MinIO or Ceph, instead of AWS
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+	// PathStyle addresses the S3 bucket in the path of the request URL instead of its host
+	// +optional
+	PathStyle bool `json:"pathStyle,omitempty"`
+	// InsecureSkipVerify skips the verification of the certificate served by Endpoint
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// AzureBlob locates the images in a container of an Azure storage account
+type AzureBlob struct {
+	// AccountName of the Azure storage account
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]{3,24}$`
+	AccountName string `json:"accountName"`
+	// Container of the storage account holding the images
+	// +kubebuilder:validation:MinLength=3
+	// +kubebuilder:validation:MaxLength=63
+	Container string `json:"container"`
+}
+
+// CredentialsSecretRef maps the source credentials to keys of a Secret
+type CredentialsSecretRef struct {
+	// Name of the Secret in the Darkroom namespace
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// AccessKeyKey is the key of the Secret holding the S3 access key
+	// +kubebuilder:default=accessKey
+	// +optional
+	AccessKeyKey string `json:"accessKeyKey,omitempty"`
+	// SecretKeyKey is the key of the Secret holding the S3 secret key
+	// +kubebuilder:default=secretKey
+	// +optional
+	SecretKeyKey string `json:"secretKeyKey,omitempty"`
+	// CredentialsJsonKey is the key of the Secret holding the GoogleCloudStorage credentials json
+	// +kubebuilder:default=credentialsJson
+	// +optional
+	CredentialsJsonKey string `json:"credentialsJsonKey,omitempty"`
+	// SASTokenKey is the key of the Secret holding the AzureBlobStorage SAS token
+	// +kubebuilder:default=sasToken
+	// +optional
+	SASTokenKey string `json:"sasTokenKey,omitempty"`
+}
+
+// SecretKeyRef references a key of a Secret in the Darkroom namespace
+type SecretKeyRef struct {
+	// Name of the Secret
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Key of the Secret
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+}
+
+// BasicAuthSecretRef maps the basic auth credentials to keys of a Secret
+type BasicAuthSecretRef struct {
+	// Name of the Secret in the Darkroom namespace
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// UsernameKey is the key of the Secret holding the username
+	// +kubebuilder:default=username
+	// +optional
+	UsernameKey string `json:"usernameKey,omitempty"`
+	// PasswordKey is the key of the Secret holding the password
+	// +kubebuilder:default=password
+	// +optional
+	PasswordKey string `json:"passwordKey,omitempty"`
+}
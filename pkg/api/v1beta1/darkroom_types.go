@@ -0,0 +1,198 @@
+/*
+MIT License
+
+Copyright (c) 2020 GO-JEK Tech
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum=WebFolder;S3;GoogleCloudStorage;AzureBlobStorage
+type Type string
+
+const (
+	WebFolder          Type = "WebFolder"
+	S3                 Type = "S3"
+	GoogleCloudStorage Type = "GoogleCloudStorage"
+	AzureBlobStorage   Type = "AzureBlobStorage"
+)
+
+type DeployState string
+
+const (
+	Deploying DeployState = "Deploying"
+	Deployed  DeployState = "Deployed"
+	Failed    DeployState = "Failed"
+	Deleting  DeployState = "Deleting"
+)
+
+// DeletionPolicy decides what happens to the objects generated for a Darkroom when it is deleted
+// +kubebuilder:validation:Enum=Delete;Orphan
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete removes the routes first, waits for the traffic to drain and then deletes the workloads
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyOrphan leaves the generated objects running, without the Darkroom as their owner
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+// DarkroomSpec defines the desired state of Darkroom
+type DarkroomSpec struct {
+	// Version is the tag of the darkroom image, an empty or latest Version is resolved into the default tag
+	// configured on the operator
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Image overrides the repository, tag or digest and pull settings of the darkroom image
+	// +optional
+	Image *Image `json:"image,omitempty"`
+
+	Source Source `json:"source"`
+	// FallbackSources are consulted in order when an image is missing from Source
+	// +optional
+	FallbackSources []Source `json:"fallbackSources,omitempty"`
+
+	// PathPrefix prepends the prefix in the URL when serving images
+	// +optional
+	PathPrefix string `json:"pathPrefix,omitempty"`
+
+	// +kubebuilder:validation:MinItems=1
+	Domains []string `json:"domains"`
+
+	// Server tunes the darkroom server
+	// +optional
+	Server *Server `json:"server,omitempty"`
+	// ExtraEnv sets darkroom settings not covered by the spec, settings managed by the operator take precedence
+	// +optional
+	ExtraEnv map[string]string `json:"extraEnv,omitempty"`
+	// Deployment configures the replicas, resources and scheduling of the darkroom pods
+	// +optional
+	Deployment *Deployment `json:"deployment,omitempty"`
+	// Autoscaling scales the darkroom pods with a HorizontalPodAutoscaler
+	// +optional
+	Autoscaling *Autoscaling `json:"autoscaling,omitempty"`
+	// DisruptionBudget limits the voluntary disruptions of the darkroom pods
+	// +optional
+	DisruptionBudget *DisruptionBudget `json:"disruptionBudget,omitempty"`
+	// TLS issues a certificate for the Domains through cert-manager and serves it on the generated Ingress
+	// +optional
+	TLS *TLS `json:"tls,omitempty"`
+	// Ingress configures the routing of Domains to darkroom
+	// +optional
+	Ingress *Ingress `json:"ingress,omitempty"`
+	// NetworkPolicy restricts the traffic of the darkroom pods, no NetworkPolicy is generated unless it is set
+	// +optional
+	NetworkPolicy *NetworkPolicy `json:"networkPolicy,omitempty"`
+	// ServiceMonitor makes the Prometheus Operator scrape darkroom, when it is installed
+	// +optional
+	ServiceMonitor *ServiceMonitor `json:"serviceMonitor,omitempty"`
+	// DeletionPolicy decides whether the generated objects are deleted or orphaned along with the Darkroom
+	// +kubebuilder:default=Delete
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// DarkroomStatus defines the observed state of Darkroom
+type DarkroomStatus struct {
+	DeployState DeployState `json:"deployState"`
+	// Domains lists the hosts admitted by the ingress controller or Gateway
+	// +optional
+	Domains []string `json:"domains,omitempty"`
+	// ObservedGeneration is the most recent generation observed by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Replicas is the number of darkroom pods targeted by the Deployment
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+	// UpdatedReplicas is the number of darkroom pods running the desired template
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+	// ReadyReplicas is the number of ready darkroom pods
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// AvailableReplicas is the number of available darkroom pods
+	// +optional
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+	// ConfigHash is the hash of the configuration and credentials the darkroom pods are rolled out with
+	// +optional
+	ConfigHash string `json:"configHash,omitempty"`
+	// Selector is the label selector of the darkroom pods, used by the scale subresource
+	// +optional
+	Selector string `json:"selector,omitempty"`
+	// Sources reports whether the source and each of the fallback sources validated successfully
+	// +optional
+	Sources []SourceStatus `json:"sources,omitempty"`
+	// LastHandledReconcileAt is the last value of the reconcile-at annotation the controller reconciled
+	// +optional
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
+	// Conditions represent the latest observations of the darkroom state
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// SourceStatus reports whether a source of the Darkroom validated successfully
+type SourceStatus struct {
+	// Path of the source in the spec, e.g. spec.source or spec.fallbackSources[0]
+	Path string `json:"path"`
+	// Type of the source
+	Type Type `json:"type"`
+	// Valid is true when the source and its credentials were validated successfully
+	Valid bool `json:"valid"`
+	// Message explains why the source is not valid
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.deployment.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.deployState`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Darkroom is the Schema for the darkrooms API
+type Darkroom struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DarkroomSpec   `json:"spec,omitempty"`
+	Status DarkroomStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// DarkroomList contains a list of Darkroom
+type DarkroomList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Darkroom `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Darkroom{}, &DarkroomList{})
+}
@@ -0,0 +1,192 @@
+package v1beta1
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Server tunes the darkroom server
+type Server struct {
+	// Debug enables the debug mode of darkroom
+	// +optional
+	Debug bool `json:"debug,omitempty"`
+	// +kubebuilder:validation:Enum=debug;info;warn;error
+	// +kubebuilder:default=info
+	// +optional
+	LogLevel string `json:"logLevel,omitempty"`
+	// Port the darkroom container listens on
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=3000
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// MetricsPort the darkroom container serves its Prometheus metrics on
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=9090
+	// +optional
+	MetricsPort int32 `json:"metricsPort,omitempty"`
+	// CacheTime is the max-age, in seconds, of the Cache-Control header of the served images
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	CacheTime *int64 `json:"cacheTime,omitempty"`
+}
+
+// CircuitBreaker tunes the hystrix circuit breaker guarding the requests to the source
+type CircuitBreaker struct {
+	// Timeout of a request to the source, in milliseconds
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=60000
+	// +kubebuilder:default=5000
+	// +optional
+	Timeout int32 `json:"timeout,omitempty"`
+	// MaxConcurrentRequests to the source
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10000
+	// +kubebuilder:default=100
+	// +optional
+	MaxConcurrentRequests int32 `json:"maxConcurrentRequests,omitempty"`
+	// RequestVolumeThreshold is the minimum number of requests in a window before the circuit can trip
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10000
+	// +kubebuilder:default=10
+	// +optional
+	RequestVolumeThreshold int32 `json:"requestVolumeThreshold,omitempty"`
+	// SleepWindow is the time after tripping the circuit before trying the source again, in milliseconds
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=300000
+	// +kubebuilder:default=10
+	// +optional
+	SleepWindow int32 `json:"sleepWindow,omitempty"`
+	// ErrorPercentThreshold is the percentage of failed requests tripping the circuit
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=25
+	// +optional
+	ErrorPercentThreshold int32 `json:"errorPercentThreshold,omitempty"`
+}
+
+// Image overrides the darkroom container image
+type Image struct {
+	// Repository of the image, defaults to the repository configured on the operator
+	// +optional
+	Repository string `json:"repository,omitempty"`
+	// Tag of the image, takes precedence over Version
+	// +optional
+	Tag string `json:"tag,omitempty"`
+	// Digest pins the image, e.g. sha256:..., takes precedence over Tag and Version
+	// +optional
+	Digest string `json:"digest,omitempty"`
+	// PullPolicy of the darkroom container
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
+	// +optional
+	PullPolicy corev1.PullPolicy `json:"pullPolicy,omitempty"`
+	// ImagePullSecrets reference Secrets in the Darkroom namespace used to pull the image
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+// Deployment configures the pods running darkroom
+type Deployment struct {
+	// Replicas is the number of darkroom pods, defaults to 1. It is ignored while spec.autoscaling is set.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Resources of the darkroom container
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// PodAnnotations are added to the darkroom pods
+	// +optional
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+	// PodLabels are added to the darkroom pods, the darkroom label is reserved
+	// +optional
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+	// Probes override the default HTTP probes of the darkroom container
+	// +optional
+	Probes *Probes `json:"probes,omitempty"`
+	// RollingUpdate configures the rolling update of the darkroom pods, defaults to a maxSurge of 25%
+	// and a maxUnavailable of 0
+	// +optional
+	RollingUpdate *appsv1.RollingUpdateDeployment `json:"rollingUpdate,omitempty"`
+}
+
+// Probes of the darkroom container, a probe without a handler probes the darkroom health endpoint
+type Probes struct {
+	// +optional
+	Liveness *corev1.Probe `json:"liveness,omitempty"`
+	// +optional
+	Readiness *corev1.Probe `json:"readiness,omitempty"`
+	// +optional
+	Startup *corev1.Probe `json:"startup,omitempty"`
+}
+
+// Autoscaling scales the darkroom pods with a HorizontalPodAutoscaler. While it is set,
+// spec.deployment.replicas is left to the autoscaler.
+type Autoscaling struct {
+	// MinReplicas is the lower limit of darkroom pods, defaults to 1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// MaxReplicas is the upper limit of darkroom pods
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+	// TargetCPUUtilizationPercentage is the average CPU utilization of the darkroom pods,
+	// relative to their requests
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+	// TargetMemoryUtilizationPercentage is the average memory utilization of the darkroom pods,
+	// relative to their requests
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+	// CustomMetrics are per-pod metrics served by a custom metrics API
+	// +optional
+	CustomMetrics []CustomMetric `json:"customMetrics,omitempty"`
+}
+
+// CustomMetric targets an average value of a metric describing the darkroom pods
+type CustomMetric struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// TargetAverageValue is the value of the metric averaged across the darkroom pods
+	TargetAverageValue resource.Quantity `json:"targetAverageValue"`
+}
+
+// DisruptionBudget limits the voluntary disruptions of the darkroom pods, e.g. by node drains, with a
+// PodDisruptionBudget. It only applies while more than one replica is requested.
+type DisruptionBudget struct {
+	// Disabled removes the PodDisruptionBudget
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+	// MinAvailable is the number or percentage of darkroom pods that must stay available
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// MaxUnavailable is the number or percentage of darkroom pods that may be unavailable,
+	// defaults to 1 unless MinAvailable is set
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// ServiceMonitor makes the Prometheus Operator scrape the metrics port of darkroom
+type ServiceMonitor struct {
+	// Labels added to the ServiceMonitor, e.g. to match the serviceMonitorSelector of a Prometheus
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Interval between scrapes, e.g. 30s, defaults to the interval of the Prometheus
+	// +kubebuilder:validation:Pattern=`^(0|(([0-9]+)h)?(([0-9]+)m)?(([0-9]+)s)?(([0-9]+)ms)?)$`
+	// +optional
+	Interval string `json:"interval,omitempty"`
+}